@@ -0,0 +1,169 @@
+// Copyright 2023 the v8go contributors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package v8go
+
+// #include <stdlib.h>
+// #include "v8go.h"
+import "C"
+import (
+	"errors"
+	"runtime"
+	"unsafe"
+)
+
+// AccessorGetter is a callback that is executed in Go when a property with
+// an accessor is read in JS. The returned value is the value of the property;
+// a nil value is read as undefined.
+type AccessorGetter func(info *PropertyCallbackInfo) *Value
+
+// AccessorSetter is a callback that is executed in Go when a property with an
+// accessor is assigned in JS; the assigned value is info.Value().
+type AccessorSetter func(info *PropertyCallbackInfo)
+
+// PropertyCallbackInfo is the argument that is passed to the callbacks of
+// accessors and property handlers.
+type PropertyCallbackInfo struct {
+	ctx   *Context
+	this  *Object
+	key   string
+	index uint32
+	value *Value
+}
+
+// Context is the current context that the callback is being executed in.
+func (i *PropertyCallbackInfo) Context() *Context {
+	return i.ctx
+}
+
+// This returns the receiver object "this", on which the property is accessed.
+func (i *PropertyCallbackInfo) This() *Object {
+	return i.this
+}
+
+// Key returns the name of the property being accessed; it is empty for
+// indexed property handlers.
+func (i *PropertyCallbackInfo) Key() string {
+	return i.key
+}
+
+// Index returns the index of the property being accessed by an indexed
+// property handler.
+func (i *PropertyCallbackInfo) Index() uint32 {
+	return i.index
+}
+
+// Value returns the value being assigned to the property, or nil when the
+// property is being read.
+func (i *PropertyCallbackInfo) Value() *Value {
+	return i.value
+}
+
+// Accessor holds the callbacks of an accessor property, see
+// WithStartupAccessors.
+type Accessor struct {
+	Getter AccessorGetter
+	Setter AccessorSetter
+}
+
+// SetAccessor adds a property with a Go getter and an optional Go setter to
+// each instance created by this template. Without a setter the property is
+// read-only.
+func (o *ObjectTemplate) SetAccessor(name string, getter AccessorGetter, setter AccessorSetter, attributes ...PropertyAttribute) {
+	if getter == nil {
+		panic("nil AccessorGetter argument not supported")
+	}
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+
+	cbref := o.iso.registerAccessor(Accessor{getter, setter})
+	C.ObjectTemplateSetAccessor(o.ptr, cname, C.int(cbref), boolToCInt(setter != nil), C.int(mergeAttributes(attributes)))
+	runtime.KeepAlive(o)
+}
+
+// SetAccessor defines a property with a Go getter and an optional Go setter on
+// the Object. Without a setter the property is read-only.
+func (o *Object) SetAccessor(key string, getter AccessorGetter, setter AccessorSetter, attributes ...PropertyAttribute) error {
+	if getter == nil {
+		panic("nil AccessorGetter argument not supported")
+	}
+	ckey := C.CString(key)
+	defer C.free(unsafe.Pointer(ckey))
+
+	cbref := o.ctx.iso.registerAccessor(Accessor{getter, setter})
+	rtn := C.ObjectSetAccessor(o.ptr, ckey, C.int(cbref), boolToCInt(setter != nil), C.int(mergeAttributes(attributes)))
+	if rtn.error.msg != nil {
+		return newJSError(rtn.error)
+	}
+	if rtn.value == 0 {
+		return errors.New("v8go: unable to define accessor on object")
+	}
+	return nil
+}
+
+func mergeAttributes(attributes []PropertyAttribute) PropertyAttribute {
+	var attrs PropertyAttribute
+	for _, a := range attributes {
+		attrs |= a
+	}
+	return attrs
+}
+
+func boolToCInt(b bool) C.int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (i *Isolate) registerAccessor(a Accessor) int {
+	i.cbMutex.Lock()
+	i.accessorSeq++
+	ref := i.accessorSeq
+	i.accessors[ref] = a
+	i.cbMutex.Unlock()
+	return ref
+}
+
+func (i *Isolate) getAccessor(ref int) Accessor {
+	i.cbMutex.RLock()
+	defer i.cbMutex.RUnlock()
+	return i.accessors[ref]
+}
+
+//export goAccessorGetterCallback
+func goAccessorGetterCallback(ctxref int, cbref int, thisPtr C.ValuePtr, key *C.char) C.ValuePtr {
+	ctx := getContext(ctxref)
+	info := &PropertyCallbackInfo{
+		ctx:  ctx,
+		this: &Object{&Value{ptr: thisPtr, ctx: ctx}},
+		key:  C.GoString(key),
+	}
+
+	// The getter is missing if the accessor was restored from a snapshot
+	// without WithStartupAccessors.
+	getter := ctx.iso.getAccessor(cbref).Getter
+	if getter == nil {
+		return nil
+	}
+	if val := getter(info); val != nil {
+		return val.ptr
+	}
+	return nil
+}
+
+//export goAccessorSetterCallback
+func goAccessorSetterCallback(ctxref int, cbref int, thisPtr C.ValuePtr, key *C.char, value C.ValuePtr) {
+	ctx := getContext(ctxref)
+	info := &PropertyCallbackInfo{
+		ctx:   ctx,
+		this:  &Object{&Value{ptr: thisPtr, ctx: ctx}},
+		key:   C.GoString(key),
+		value: &Value{ptr: value, ctx: ctx},
+	}
+
+	if setter := ctx.iso.getAccessor(cbref).Setter; setter != nil {
+		setter(info)
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2023 the v8go contributors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package v8go
+
+/*
+#include "v8go.h"
+*/
+import "C"
+import (
+	"io"
+	"time"
+	"unsafe"
+)
+
+// AllocationProfile is the call tree of the allocations sampled by a
+// HeapProfiler.
+type AllocationProfile struct {
+	root           *AllocationProfileNode
+	sampleInterval uint64
+}
+
+// GetRootNode returns the root node of the call tree.
+func (p *AllocationProfile) GetRootNode() *AllocationProfileNode {
+	return p.root
+}
+
+// AllocationProfileNode is a function of the call tree of an
+// AllocationProfile, with the allocations it made.
+type AllocationProfileNode struct {
+	// The id of the current node, unique within the tree.
+	nodeId int
+
+	// The id of the script where the function originates.
+	scriptId int
+
+	// The resource name for script from where the function originates.
+	scriptResourceName string
+
+	// The function name (empty string for anonymous functions.)
+	functionName string
+
+	// The number of the line where the function originates.
+	lineNumber int
+
+	// The number of the column where the function originates.
+	columnNumber int
+
+	// The allocations made by the function itself.
+	allocations []Allocation
+
+	// The children node of this node.
+	children []*AllocationProfileNode
+
+	// The parent node of this node.
+	parent *AllocationProfileNode
+}
+
+// Allocation is the number of sampled objects of a size.
+type Allocation struct {
+	// The size of the objects in bytes.
+	Size int
+
+	// The count of sampled objects.
+	Count int
+}
+
+// Returns node id.
+func (n *AllocationProfileNode) GetNodeId() int {
+	return n.nodeId
+}
+
+// Returns id for script from where the function originates.
+func (n *AllocationProfileNode) GetScriptId() int {
+	return n.scriptId
+}
+
+// Returns function name (empty string for anonymous functions.)
+func (n *AllocationProfileNode) GetFunctionName() string {
+	return n.functionName
+}
+
+// Returns resource name for script from where the function originates.
+func (n *AllocationProfileNode) GetScriptResourceName() string {
+	return n.scriptResourceName
+}
+
+// Returns number of the line where the function originates.
+func (n *AllocationProfileNode) GetLineNumber() int {
+	return n.lineNumber
+}
+
+// Returns number of the column where the function originates.
+func (n *AllocationProfileNode) GetColumnNumber() int {
+	return n.columnNumber
+}
+
+// Returns the allocations made by the function itself, by size.
+func (n *AllocationProfileNode) GetAllocations() []Allocation {
+	return n.allocations
+}
+
+// Retrieves the ancestor node, or nil if the root.
+func (n *AllocationProfileNode) GetParent() *AllocationProfileNode {
+	return n.parent
+}
+
+func (n *AllocationProfileNode) GetChildrenCount() int {
+	return len(n.children)
+}
+
+// Retrieves a child node by index.
+func (n *AllocationProfileNode) GetChild(index int) *AllocationProfileNode {
+	return n.children[index]
+}
+
+// walk calls fn for the node and its descendants, depth first.
+func (n *AllocationProfileNode) walk(fn func(*AllocationProfileNode)) {
+	fn(n)
+	for _, child := range n.children {
+		child.walk(fn)
+	}
+}
+
+func newAllocationProfileNode(node *C.AllocationProfileNode, parent *AllocationProfileNode) *AllocationProfileNode {
+	n := &AllocationProfileNode{
+		nodeId:             int(node.nodeId),
+		scriptId:           int(node.scriptId),
+		scriptResourceName: C.GoString(node.scriptResourceName),
+		functionName:       C.GoString(node.functionName),
+		lineNumber:         int(node.lineNumber),
+		columnNumber:       int(node.columnNumber),
+		parent:             parent,
+	}
+
+	if count := int(node.allocationsCount); count > 0 {
+		n.allocations = make([]Allocation, count)
+		for i, a := range unsafe.Slice(node.allocations, count) {
+			n.allocations[i] = Allocation{Size: int(a.size), Count: int(a.count)}
+		}
+	}
+
+	if count := int(node.childrenCount); count > 0 {
+		n.children = make([]*AllocationProfileNode, count)
+		for i, child := range unsafe.Slice(node.children, count) {
+			n.children[i] = newAllocationProfileNode(child, n)
+		}
+	}
+
+	return n
+}
+
+// WritePprof writes the profile in the gzipped protocol buffer format of
+// pprof, as a heap profile like those of Go, so that it can be analyzed with
+// `go tool pprof`. Each size of object allocated by a function is a sample,
+// with its stack of JavaScript functions, the count and bytes of the
+// objects, and a "bytes" label with the size.
+func (p *AllocationProfile) WritePprof(w io.Writer) error {
+	b := newProfileBuilder()
+	b.sampleTypes([2]string{"inuse_objects", "count"}, [2]string{"inuse_space", "bytes"})
+	b.period("space", "bytes", int64(p.sampleInterval))
+	b.timing(time.Now(), 0)
+
+	p.root.walk(func(n *AllocationProfileNode) {
+		if len(n.allocations) == 0 {
+			return
+		}
+		var stack []uint64
+		for f := n; f.parent != nil; f = f.parent {
+			fn := pprofFunction{
+				name:     f.functionName,
+				filename: f.scriptResourceName,
+				line:     int64(f.lineNumber),
+			}
+			if fn.name == "" {
+				fn.name = "(anonymous)"
+			}
+			stack = append(stack, b.location(fn, int64(f.lineNumber), int64(f.columnNumber)))
+		}
+		if len(stack) == 0 {
+			return
+		}
+		for _, a := range n.allocations {
+			b.sample(stack,
+				[]int64{int64(a.Count), int64(a.Count) * int64(a.Size)},
+				map[string]int64{"bytes": int64(a.Size)})
+		}
+	})
+	return b.write(w)
+}
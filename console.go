@@ -0,0 +1,271 @@
+// Copyright 2023 the v8go contributors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package v8go
+
+// #include "v8go.h"
+import "C"
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// ConsoleMethod is a method of the `console` object.
+type ConsoleMethod int
+
+const (
+	ConsoleDebug ConsoleMethod = iota
+	ConsoleError
+	ConsoleInfo
+	ConsoleLog
+	ConsoleWarn
+	ConsoleDir
+	ConsoleDirXML
+	ConsoleTable
+	ConsoleTrace
+	ConsoleGroup
+	ConsoleGroupCollapsed
+	ConsoleGroupEnd
+	ConsoleClear
+	ConsoleCount
+	ConsoleCountReset
+	ConsoleAssert
+	ConsoleProfile
+	ConsoleProfileEnd
+	ConsoleTime
+	ConsoleTimeLog
+	ConsoleTimeEnd
+	ConsoleTimeStamp
+)
+
+var consoleMethodNames = [...]string{
+	ConsoleDebug:          "debug",
+	ConsoleError:          "error",
+	ConsoleInfo:           "info",
+	ConsoleLog:            "log",
+	ConsoleWarn:           "warn",
+	ConsoleDir:            "dir",
+	ConsoleDirXML:         "dirxml",
+	ConsoleTable:          "table",
+	ConsoleTrace:          "trace",
+	ConsoleGroup:          "group",
+	ConsoleGroupCollapsed: "groupCollapsed",
+	ConsoleGroupEnd:       "groupEnd",
+	ConsoleClear:          "clear",
+	ConsoleCount:          "count",
+	ConsoleCountReset:     "countReset",
+	ConsoleAssert:         "assert",
+	ConsoleProfile:        "profile",
+	ConsoleProfileEnd:     "profileEnd",
+	ConsoleTime:           "time",
+	ConsoleTimeLog:        "timeLog",
+	ConsoleTimeEnd:        "timeEnd",
+	ConsoleTimeStamp:      "timeStamp",
+}
+
+// String returns the name of the method in JavaScript, eg. "log".
+func (m ConsoleMethod) String() string {
+	if m < 0 || int(m) >= len(consoleMethodNames) {
+		return "ConsoleMethod(" + strconv.Itoa(int(m)) + ")"
+	}
+	return consoleMethodNames[m]
+}
+
+// ConsoleLevel is the severity of a console message.
+type ConsoleLevel int
+
+const (
+	ConsoleLevelDebug ConsoleLevel = iota
+	ConsoleLevelLog
+	ConsoleLevelInfo
+	ConsoleLevelWarning
+	ConsoleLevelError
+)
+
+// Level returns the severity of the messages of the method: console.debug,
+// console.info and console.warn have their own level, console.error and
+// failed assertions are errors, and all other methods log.
+func (m ConsoleMethod) Level() ConsoleLevel {
+	switch m {
+	case ConsoleDebug:
+		return ConsoleLevelDebug
+	case ConsoleInfo:
+		return ConsoleLevelInfo
+	case ConsoleWarn:
+		return ConsoleLevelWarning
+	case ConsoleError, ConsoleAssert:
+		return ConsoleLevelError
+	default:
+		return ConsoleLevelLog
+	}
+}
+
+// ConsoleMessage is a call of a console method by a script.
+type ConsoleMessage struct {
+	Method ConsoleMethod
+	Level  ConsoleLevel
+	// Args are the arguments of the call; for console.assert they are the
+	// arguments following the failed assertion. The values are only valid
+	// until the Context is closed.
+	Args []*Value
+	// Frame is the stack frame of the caller; it is nil if the method was
+	// not called from a script, eg. with Function.Call.
+	Frame *StackFrame
+
+	ctx *Context
+}
+
+// String formats the arguments of the message like browsers do: a first
+// string argument can hold substitutions for the following arguments, such
+// as %s, %d, %i, %f, %o, %O and %c, and the remaining arguments are appended
+// separated by spaces. Objects are formatted as JSON where possible.
+func (m *ConsoleMessage) String() string {
+	args := m.Args
+	var sb strings.Builder
+	if m.Method == ConsoleAssert {
+		sb.WriteString("Assertion failed")
+		if len(args) > 0 {
+			sb.WriteString(": ")
+		}
+	}
+	if len(args) > 0 && args[0].IsString() {
+		args = m.substitute(&sb, args[0].String(), args[1:])
+	} else if len(args) > 0 {
+		sb.WriteString(m.formatArg(args[0]))
+		args = args[1:]
+	}
+	for _, arg := range args {
+		sb.WriteByte(' ')
+		sb.WriteString(m.formatArg(arg))
+	}
+	return sb.String()
+}
+
+// substitute writes format with its substitutions replaced by the args,
+// returning the args that were not substituted.
+func (m *ConsoleMessage) substitute(sb *strings.Builder, format string, args []*Value) []*Value {
+	for {
+		i := strings.IndexByte(format, '%')
+		if i < 0 || i == len(format)-1 {
+			sb.WriteString(format)
+			return args
+		}
+		sb.WriteString(format[:i])
+		verb := format[i+1]
+		format = format[i+2:]
+		if verb == '%' {
+			sb.WriteByte('%')
+			continue
+		}
+		if !strings.ContainsRune("sdifoOc", rune(verb)) || len(args) == 0 {
+			sb.WriteByte('%')
+			sb.WriteByte(verb)
+			continue
+		}
+		arg := args[0]
+		args = args[1:]
+		switch verb {
+		case 's':
+			if arg.IsString() {
+				sb.WriteString(arg.String())
+			} else {
+				sb.WriteString(m.formatArg(arg))
+			}
+		case 'd', 'i':
+			if f := arg.Number(); math.IsNaN(f) || math.IsInf(f, 0) {
+				sb.WriteString("NaN")
+			} else {
+				sb.WriteString(strconv.FormatFloat(math.Trunc(f), 'f', -1, 64))
+			}
+		case 'f':
+			sb.WriteString(strconv.FormatFloat(arg.Number(), 'g', -1, 64))
+		case 'o', 'O':
+			sb.WriteString(m.formatArg(arg))
+		case 'c':
+			// CSS styles are not applicable
+		}
+	}
+}
+
+func (m *ConsoleMessage) formatArg(arg *Value) string {
+	if arg.IsString() {
+		return arg.String()
+	}
+	if arg.IsObject() && !arg.IsFunction() && !arg.IsNativeError() {
+		if s, err := JSONStringify(m.ctx, arg); err == nil {
+			return s
+		}
+	}
+	return arg.DetailString()
+}
+
+// ConsoleDelegate receives the messages of the console methods called by
+// scripts, see Isolate.SetConsoleDelegate.
+type ConsoleDelegate interface {
+	// ConsoleMessage is called synchronously by the console method, in the
+	// Context of the script that called it.
+	ConsoleMessage(ctx *Context, msg *ConsoleMessage)
+}
+
+// ConsoleDelegateFunc is an adapter to use a function as a ConsoleDelegate.
+type ConsoleDelegateFunc func(ctx *Context, msg *ConsoleMessage)
+
+// ConsoleMessage calls f(ctx, msg).
+func (f ConsoleDelegateFunc) ConsoleMessage(ctx *Context, msg *ConsoleMessage) {
+	f(ctx, msg)
+}
+
+// SetConsoleDelegate routes the calls of the console methods by scripts in
+// all the contexts of the Isolate to the delegate, or discards them if it is
+// nil, which is the default. console.assert is only passed on when the
+// assertion fails.
+func (i *Isolate) SetConsoleDelegate(delegate ConsoleDelegate) {
+	i.cbMutex.Lock()
+	i.consoleDelegate = delegate
+	i.cbMutex.Unlock()
+	C.IsolateSetConsoleDelegate(i.ptr, boolToCInt(delegate != nil))
+}
+
+func (i *Isolate) getConsoleDelegate() ConsoleDelegate {
+	i.cbMutex.RLock()
+	defer i.cbMutex.RUnlock()
+	return i.consoleDelegate
+}
+
+//export goConsoleMessage
+func goConsoleMessage(ctxref int, method C.ConsoleMethod, args *C.ValuePtr, argsCount C.int, frame *C.ErrorStackFrame) {
+	ctx := getContext(ctxref)
+	delegate := ctx.iso.getConsoleDelegate()
+	if delegate == nil {
+		return
+	}
+
+	msg := &ConsoleMessage{
+		Method: ConsoleMethod(method),
+		Level:  ConsoleMethod(method).Level(),
+		Args:   make([]*Value, int(argsCount)),
+		ctx:    ctx,
+	}
+	if argsCount > 0 {
+		for i, ptr := range unsafe.Slice(args, int(argsCount)) {
+			msg.Args[i] = &Value{ptr: ptr, ctx: ctx}
+		}
+	}
+	if msg.Method == ConsoleAssert {
+		if len(msg.Args) > 0 && msg.Args[0].Boolean() {
+			return
+		}
+		if len(msg.Args) > 0 {
+			msg.Args = msg.Args[1:]
+		}
+	}
+	if frame != nil {
+		f := newStackFrame(*frame)
+		ctx.iso.mapStackFrame(&f)
+		msg.Frame = &f
+	}
+	delegate.ConsoleMessage(ctx, msg)
+}
@@ -0,0 +1,100 @@
+// Copyright 2023 the v8go contributors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package v8go_test
+
+import (
+	"testing"
+
+	v8 "rogchap.com/v8go"
+)
+
+func TestIsolateSetConsoleDelegate(t *testing.T) {
+	t.Parallel()
+
+	iso := v8.NewIsolate()
+	defer iso.Dispose()
+	ctx := v8.NewContext(iso)
+	defer ctx.Close()
+
+	type message struct {
+		method v8.ConsoleMethod
+		level  v8.ConsoleLevel
+		text   string
+		frame  *v8.StackFrame
+	}
+	var msgs []message
+	iso.SetConsoleDelegate(v8.ConsoleDelegateFunc(func(c *v8.Context, msg *v8.ConsoleMessage) {
+		if c != ctx {
+			t.Errorf("unexpected context")
+		}
+		msgs = append(msgs, message{msg.Method, msg.Level, msg.String(), msg.Frame})
+	}))
+
+	_, err := ctx.RunScript(`function run() {
+  console.log("hello %s, %d%%", "world", 42.5, {a: 1});
+  console.warn(1, true, null);
+  console.assert(true, "not logged");
+  console.assert(false, "failed");
+  console.time("t");
+}
+run();`, "console.js")
+	fatalIf(t, err)
+
+	expected := []struct {
+		message
+		line int
+	}{
+		{message{v8.ConsoleLog, v8.ConsoleLevelLog, `hello world, 42% {"a":1}`, nil}, 2},
+		{message{v8.ConsoleWarn, v8.ConsoleLevelWarning, "1 true null", nil}, 3},
+		{message{v8.ConsoleAssert, v8.ConsoleLevelError, "Assertion failed: failed", nil}, 5},
+		{message{v8.ConsoleTime, v8.ConsoleLevelLog, "t", nil}, 6},
+	}
+	if len(msgs) != len(expected) {
+		t.Fatalf("expected %d messages, got %d: %+v", len(expected), len(msgs), msgs)
+	}
+	for i, msg := range msgs {
+		if msg.method != expected[i].method || msg.level != expected[i].level || msg.text != expected[i].text {
+			t.Errorf("unexpected message %d: %+v", i, msg)
+		}
+		if msg.frame == nil || msg.frame.ScriptName != "console.js" || msg.frame.FunctionName != "run" || msg.frame.Line != expected[i].line {
+			t.Errorf("unexpected frame of message %d: %+v", i, msg.frame)
+		}
+	}
+	if msgs[0].method.String() != "log" {
+		t.Errorf("unexpected method name %q", msgs[0].method)
+	}
+
+	msgs = nil
+	iso.SetConsoleDelegate(nil)
+	_, err = ctx.RunScript(`console.log("discarded")`, "")
+	fatalIf(t, err)
+	if len(msgs) != 0 {
+		t.Errorf("unexpected messages after removing the delegate: %+v", msgs)
+	}
+}
+
+func TestIsolateSetConsoleDelegateInspector(t *testing.T) {
+	t.Parallel()
+
+	iso := v8.NewIsolate()
+	defer iso.Dispose()
+	ctx := v8.NewContext(iso)
+	defer ctx.Close()
+
+	var texts []string
+	delegate := v8.ConsoleDelegateFunc(func(c *v8.Context, msg *v8.ConsoleMessage) {
+		texts = append(texts, msg.String())
+	})
+	ins := iso.Inspector()
+	iso.SetConsoleDelegate(delegate)
+	ins.Dispose()
+
+	// the delegate set after the inspector is kept once it is disposed
+	_, err := ctx.RunScript(`console.log("after")`, "")
+	fatalIf(t, err)
+	if len(texts) != 1 || texts[0] != "after" {
+		t.Errorf("unexpected messages: %q", texts)
+	}
+}
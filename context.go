@@ -8,6 +8,8 @@ package v8go
 // #include "v8go.h"
 import "C"
 import (
+	"context"
+	"fmt"
 	"runtime"
 	"sync"
 	"unsafe"
@@ -31,11 +33,17 @@ type Context struct {
 	ref int
 	ptr C.ContextPtr
 	iso *Isolate
+
+	// moduleResolver is set while a module is being instantiated.
+	moduleResolver ModuleResolver
 }
 
 type contextOptions struct {
 	iso   *Isolate
 	gTmpl *ObjectTemplate
+
+	fromSnapshot  bool
+	snapshotIndex int
 }
 
 // ContextOption sets options such as Isolate and Global Template to the NewContext
@@ -66,13 +74,26 @@ func NewContext(opt ...ContextOption) *Context {
 	ref := ctxSeq
 	ctxMutex.Unlock()
 
+	var ptr C.ContextPtr
+	if opts.fromSnapshot {
+		ptr = C.NewContextFromSnapshot(opts.iso.ptr, C.size_t(opts.snapshotIndex), C.int(ref))
+		if ptr == nil {
+			panic(fmt.Sprintf("v8go: no context at index %d in the snapshot of the Isolate", opts.snapshotIndex))
+		}
+	} else {
+		ptr = C.NewContext(opts.iso.ptr, opts.gTmpl.ptr, C.int(ref))
+	}
+
 	ctx := &Context{
 		ref: ref,
-		ptr: C.NewContext(opts.iso.ptr, opts.gTmpl.ptr, C.int(ref)),
+		ptr: ptr,
 		iso: opts.iso,
 	}
 	ctx.register()
 	runtime.KeepAlive(opts.gTmpl)
+	if sc := opts.iso.snapshotCreator; sc != nil {
+		sc.ctxs = append(sc.ctxs, ctx)
+	}
 	return ctx
 }
 
@@ -91,6 +112,7 @@ func (c *Context) RetainedValueCount() int {
 // reference for the script and used in the stack trace if there is an error.
 // error will be of type `JSError` if not nil.
 func (c *Context) RunScript(source string, origin string) (*Value, error) {
+	c.iso.recordCoverageSource(origin, source)
 	cSource := C.CString(source)
 	cOrigin := C.CString(origin)
 	defer C.free(unsafe.Pointer(cSource))
@@ -100,6 +122,15 @@ func (c *Context) RunScript(source string, origin string) (*Value, error) {
 	return valueResult(c, rtn)
 }
 
+// RunScriptContext is like RunScript, but terminates the execution when ctx is
+// cancelled or its deadline is exceeded; the returned error then wraps ctx.Err(),
+// eg. context.DeadlineExceeded.
+func (c *Context) RunScriptContext(ctx context.Context, source string, origin string) (*Value, error) {
+	return c.iso.runWithContext(ctx, func() (*Value, error) {
+		return c.RunScript(source, origin)
+	})
+}
+
 // Global returns the global proxy object.
 // Global proxy object is a thin wrapper whose prototype points to actual
 // context's global object with the properties like Object, etc. This is
@@ -121,7 +152,15 @@ func (c *Context) PerformMicrotaskCheckpoint() {
 
 // Close will dispose the context and free the memory.
 // Access to any values associated with the context after calling Close may panic.
+// Closing a context again, or after the SnapshotCreator of its Isolate has
+// closed it, does nothing.
 func (c *Context) Close() {
+	if c.ptr == nil {
+		return
+	}
+	if ins := c.iso.getInspector(); ins != nil {
+		ins.ContextDestroyed(c)
+	}
 	c.deregister()
 	C.ContextFree(c.ptr)
 	c.ptr = nil
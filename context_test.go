@@ -5,9 +5,12 @@
 package v8go_test
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	v8 "rogchap.com/v8go"
 )
@@ -71,6 +74,34 @@ func TestJSExceptions(t *testing.T) {
 	}
 }
 
+func TestContextRunScriptContext(t *testing.T) {
+	t.Parallel()
+	ctx := v8.NewContext(nil)
+	defer ctx.Isolate().Dispose()
+	defer ctx.Close()
+
+	timeout, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := ctx.RunScriptContext(timeout, `while (true) {}`, "forever.js")
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, v8.ErrExecutionTerminated) {
+		t.Errorf("expected error to wrap context.DeadlineExceeded, got %v", err)
+	}
+
+	// The isolate can still be used after the execution was terminated.
+	val, err := ctx.RunScriptContext(context.Background(), `1 + 1`, "add.js")
+	fatalIf(t, err)
+	if val.Int32() != 2 {
+		t.Errorf("unexpected value: %v", val)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ctx.RunScriptContext(cancelled, `1 + 1`, "add.js"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected error to wrap context.Canceled, got %v", err)
+	}
+}
+
 func TestContextRegistry(t *testing.T) {
 	t.Parallel()
 
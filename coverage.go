@@ -0,0 +1,296 @@
+// Copyright 2023 the v8go contributors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package v8go
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// CoverageMode is the precision of the code coverage collected by an
+// Isolate.
+type CoverageMode int
+
+const (
+	// CoverageBestEffort reports which functions ran, as far as V8 still
+	// knows: it has no overhead, but functions that were optimized or
+	// garbage collected may be missing or reported with wrong counts.
+	CoverageBestEffort CoverageMode = iota
+	// CoverageCount counts the calls of each function.
+	CoverageCount
+	// CoverageBlock counts the executions of each block of code, such as
+	// the branches of conditionals and the bodies of loops.
+	CoverageBlock
+)
+
+// Coverage is the code coverage of the scripts of an Isolate, see
+// Isolate.StartCoverage.
+type Coverage struct {
+	Scripts []ScriptCoverage
+
+	// sources are the sources of the scripts by origin.
+	sources map[string]string
+}
+
+// ScriptCoverage is the coverage of a script.
+type ScriptCoverage struct {
+	ScriptID int
+	// URL is the origin of the script, as passed to Context.RunScript,
+	// Isolate.CompileUnboundScript or Isolate.CompileModule.
+	URL       string
+	Functions []FunctionCoverage
+}
+
+// FunctionCoverage is the coverage of a function of a script. The script
+// itself is reported as a function without name covering the whole script.
+type FunctionCoverage struct {
+	FunctionName string
+	// Ranges are the ranges of the function: the first range is the whole
+	// function, with its count of calls, and in CoverageBlock mode the
+	// following ranges are its blocks, nested ranges overriding the count of
+	// the ranges that contain them.
+	Ranges          []CoverageRange
+	IsBlockCoverage bool
+}
+
+// CoverageRange is a range of the source of a script with the number of
+// times it was executed. The offsets are in UTF-16 code units, the end
+// being exclusive.
+type CoverageRange struct {
+	StartOffset int
+	EndOffset   int
+	Count       int
+}
+
+// coverageSession collects the coverage of an Isolate through a session of
+// its Inspector, with the Profiler domain of the Chrome DevTools Protocol.
+type coverageSession struct {
+	mode    CoverageMode
+	session *InspectorSession
+
+	mu      sync.Mutex
+	seq     int
+	calls   map[int]chan cdpResponse
+	sources map[string]string
+}
+
+type cdpResponse struct {
+	ID     int             `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+var errCoverageNotStarted = errors.New("v8go: coverage is not started")
+
+var errInspectorDisposed = errors.New("v8go: inspector has been disposed")
+
+// StartCoverage starts collecting the code coverage of the scripts that run
+// in the Isolate, in the given mode, until StopCoverage is called.
+//
+// Coverage is collected with the Inspector of the Isolate, which is created
+// if needed (see Isolate.Inspector), so it must not be started or taken from
+// a callback of a script that runs in the Isolate. The ConsoleDelegate of the
+// Isolate is kept when the Inspector is created. The sources of the scripts
+// compiled while coverage is collected are kept to render them with
+// Coverage.WriteLCOV and Coverage.WriteIstanbul.
+func (i *Isolate) StartCoverage(mode CoverageMode) error {
+	if i.getCoverage() != nil {
+		return errors.New("v8go: coverage is already started")
+	}
+	c := &coverageSession{
+		mode:    mode,
+		calls:   make(map[int]chan cdpResponse),
+		sources: make(map[string]string),
+	}
+	// Creating the Inspector unsets the ConsoleDelegate, which is set again
+	// since the session of the coverage doesn't report console messages.
+	delegate := i.getConsoleDelegate()
+	ins := i.Inspector()
+	if delegate != nil {
+		i.SetConsoleDelegate(delegate)
+	}
+	c.session = ins.Connect(c.receive)
+
+	if _, err := c.call("Profiler.enable", nil); err != nil {
+		c.session.Disconnect()
+		return err
+	}
+	if mode != CoverageBestEffort {
+		params := map[string]bool{"callCount": true, "detailed": mode == CoverageBlock}
+		if _, err := c.call("Profiler.startPreciseCoverage", params); err != nil {
+			c.session.Disconnect()
+			return err
+		}
+	}
+
+	i.cbMutex.Lock()
+	i.coverage = c
+	i.cbMutex.Unlock()
+	return nil
+}
+
+// TakeCoverage returns the coverage collected since StartCoverage. In the
+// precise modes the counts are reset, so that the next call returns the
+// coverage collected since this one.
+func (i *Isolate) TakeCoverage() (*Coverage, error) {
+	c := i.getCoverage()
+	if c == nil {
+		return nil, errCoverageNotStarted
+	}
+	method := "Profiler.takePreciseCoverage"
+	if c.mode == CoverageBestEffort {
+		method = "Profiler.getBestEffortCoverage"
+	}
+	result, err := c.call(method, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var res struct {
+		Result []struct {
+			ScriptID  string `json:"scriptId"`
+			URL       string `json:"url"`
+			Functions []struct {
+				FunctionName string `json:"functionName"`
+				Ranges       []struct {
+					StartOffset int `json:"startOffset"`
+					EndOffset   int `json:"endOffset"`
+					Count       int `json:"count"`
+				} `json:"ranges"`
+				IsBlockCoverage bool `json:"isBlockCoverage"`
+			} `json:"functions"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(result, &res); err != nil {
+		return nil, fmt.Errorf("v8go: invalid coverage: %w", err)
+	}
+
+	cov := &Coverage{
+		Scripts: make([]ScriptCoverage, len(res.Result)),
+		sources: make(map[string]string),
+	}
+	for i, s := range res.Result {
+		id, _ := strconv.Atoi(s.ScriptID)
+		sc := ScriptCoverage{
+			ScriptID:  id,
+			URL:       s.URL,
+			Functions: make([]FunctionCoverage, len(s.Functions)),
+		}
+		for j, f := range s.Functions {
+			fc := FunctionCoverage{
+				FunctionName:    f.FunctionName,
+				Ranges:          make([]CoverageRange, len(f.Ranges)),
+				IsBlockCoverage: f.IsBlockCoverage,
+			}
+			for k, r := range f.Ranges {
+				fc.Ranges[k] = CoverageRange{r.StartOffset, r.EndOffset, r.Count}
+			}
+			sc.Functions[j] = fc
+		}
+		cov.Scripts[i] = sc
+	}
+	c.mu.Lock()
+	for _, s := range cov.Scripts {
+		if src, ok := c.sources[s.URL]; ok {
+			cov.sources[s.URL] = src
+		}
+	}
+	c.mu.Unlock()
+	return cov, nil
+}
+
+// StopCoverage stops collecting the code coverage and discards it.
+func (i *Isolate) StopCoverage() error {
+	c := i.getCoverage()
+	if c == nil {
+		return errCoverageNotStarted
+	}
+	i.cbMutex.Lock()
+	i.coverage = nil
+	i.cbMutex.Unlock()
+
+	defer c.session.Disconnect()
+	if c.mode != CoverageBestEffort {
+		if _, err := c.call("Profiler.stopPreciseCoverage", nil); err != nil {
+			return err
+		}
+	}
+	_, err := c.call("Profiler.disable", nil)
+	return err
+}
+
+func (i *Isolate) getCoverage() *coverageSession {
+	i.cbMutex.RLock()
+	defer i.cbMutex.RUnlock()
+	return i.coverage
+}
+
+// recordCoverageSource keeps the source of a script compiled while coverage
+// is collected.
+func (i *Isolate) recordCoverageSource(origin, source string) {
+	if c := i.getCoverage(); c != nil {
+		c.mu.Lock()
+		c.sources[origin] = source
+		c.mu.Unlock()
+	}
+}
+
+// call sends a CDP request and waits for its response, failing if the
+// Inspector is disposed before it responds.
+func (c *coverageSession) call(method string, params interface{}) (json.RawMessage, error) {
+	ch := make(chan cdpResponse, 1)
+	c.mu.Lock()
+	c.seq++
+	id := c.seq
+	c.calls[id] = ch
+	c.mu.Unlock()
+
+	req, err := json.Marshal(struct {
+		ID     int         `json:"id"`
+		Method string      `json:"method"`
+		Params interface{} `json:"params,omitempty"`
+	}{id, method, params})
+	if err != nil {
+		return nil, err
+	}
+	var res cdpResponse
+	if c.session.dispatch(req) {
+		select {
+		case res = <-ch:
+		case <-c.session.ins.disposed:
+		}
+	}
+	if res.ID == 0 {
+		c.mu.Lock()
+		delete(c.calls, id)
+		c.mu.Unlock()
+		return nil, errInspectorDisposed
+	}
+	if res.Error != nil {
+		return nil, fmt.Errorf("v8go: %s: %s", method, res.Error.Message)
+	}
+	return res.Result, nil
+}
+
+// receive is called on the thread of the Isolate with the messages of the
+// session; notifications are ignored.
+func (c *coverageSession) receive(message []byte) {
+	var res cdpResponse
+	if err := json.Unmarshal(message, &res); err != nil || res.ID == 0 {
+		return
+	}
+	c.mu.Lock()
+	ch := c.calls[res.ID]
+	delete(c.calls, res.ID)
+	c.mu.Unlock()
+	if ch != nil {
+		ch <- res
+	}
+}
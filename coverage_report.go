@@ -0,0 +1,251 @@
+// Copyright 2023 the v8go contributors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package v8go
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"unicode/utf16"
+)
+
+// WriteLCOV writes the coverage in the LCOV tracefile format, for tools such
+// as genhtml and most CI coverage services. Each script is a source file
+// named after its origin; the scripts whose source is unknown, because they
+// were compiled before coverage started or without an origin, are skipped.
+// In CoverageBlock mode the blocks of the functions are written as branches.
+func (c *Coverage) WriteLCOV(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, s := range c.Scripts {
+		r := c.report(s)
+		if r == nil {
+			continue
+		}
+		fmt.Fprintf(bw, "TN:\nSF:%s\n", s.URL)
+		var hit int
+		for _, f := range r.functions {
+			fmt.Fprintf(bw, "FN:%d,%s\n", f.start.Line, f.name)
+		}
+		for _, f := range r.functions {
+			fmt.Fprintf(bw, "FNDA:%d,%s\n", f.count, f.name)
+			if f.count > 0 {
+				hit++
+			}
+		}
+		fmt.Fprintf(bw, "FNF:%d\nFNH:%d\n", len(r.functions), hit)
+
+		hit = 0
+		for i, b := range r.branches {
+			fmt.Fprintf(bw, "BRDA:%d,%d,0,%d\n", b.start.Line, i, b.count)
+			if b.count > 0 {
+				hit++
+			}
+		}
+		if len(r.branches) > 0 {
+			fmt.Fprintf(bw, "BRF:%d\nBRH:%d\n", len(r.branches), hit)
+		}
+
+		hit = 0
+		for _, l := range r.lines {
+			fmt.Fprintf(bw, "DA:%d,%d\n", l.start.Line, l.count)
+			if l.count > 0 {
+				hit++
+			}
+		}
+		fmt.Fprintf(bw, "LF:%d\nLH:%d\nend_of_record\n", len(r.lines), hit)
+	}
+	return bw.Flush()
+}
+
+type istanbulPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type istanbulLocation struct {
+	Start istanbulPosition `json:"start"`
+	End   istanbulPosition `json:"end"`
+}
+
+type istanbulFunction struct {
+	Name string           `json:"name"`
+	Decl istanbulLocation `json:"decl"`
+	Loc  istanbulLocation `json:"loc"`
+	Line int              `json:"line"`
+}
+
+type istanbulBranch struct {
+	Loc       istanbulLocation   `json:"loc"`
+	Type      string             `json:"type"`
+	Locations []istanbulLocation `json:"locations"`
+	Line      int                `json:"line"`
+}
+
+type istanbulFile struct {
+	Path         string                      `json:"path"`
+	StatementMap map[string]istanbulLocation `json:"statementMap"`
+	FnMap        map[string]istanbulFunction `json:"fnMap"`
+	BranchMap    map[string]istanbulBranch   `json:"branchMap"`
+	S            map[string]int              `json:"s"`
+	F            map[string]int              `json:"f"`
+	B            map[string][]int            `json:"b"`
+}
+
+// WriteIstanbul writes the coverage as the JSON of Istanbul's
+// coverage-final.json, which nyc and the Istanbul reporters turn into HTML
+// and text reports. The scripts are keyed by their origin, the lines being
+// the statements; the scripts whose source is unknown are skipped as with
+// WriteLCOV.
+func (c *Coverage) WriteIstanbul(w io.Writer) error {
+	files := make(map[string]istanbulFile)
+	for _, s := range c.Scripts {
+		r := c.report(s)
+		if r == nil {
+			continue
+		}
+		f := istanbulFile{
+			Path:         s.URL,
+			StatementMap: make(map[string]istanbulLocation),
+			FnMap:        make(map[string]istanbulFunction),
+			BranchMap:    make(map[string]istanbulBranch),
+			S:            make(map[string]int),
+			F:            make(map[string]int),
+			B:            make(map[string][]int),
+		}
+		for i, l := range r.lines {
+			key := strconv.Itoa(i)
+			f.StatementMap[key] = l.location()
+			f.S[key] = l.count
+		}
+		for i, fn := range r.functions {
+			key := strconv.Itoa(i)
+			f.FnMap[key] = istanbulFunction{
+				Name: fn.name,
+				Decl: fn.location(),
+				Loc:  fn.location(),
+				Line: fn.start.Line,
+			}
+			f.F[key] = fn.count
+		}
+		for i, b := range r.branches {
+			key := strconv.Itoa(i)
+			f.BranchMap[key] = istanbulBranch{
+				Loc:       b.location(),
+				Type:      "branch",
+				Locations: []istanbulLocation{b.location()},
+				Line:      b.start.Line,
+			}
+			f.B[key] = []int{b.count}
+		}
+		files[s.URL] = f
+	}
+	return json.NewEncoder(w).Encode(files)
+}
+
+// coverageReport is the coverage of a script by line, function and block.
+type coverageReport struct {
+	lines     []coverageSpan
+	functions []coverageSpan
+	branches  []coverageSpan
+}
+
+// coverageSpan is a span of a script with its execution count. Lines are
+// 1-based and columns 0-based, as in Istanbul.
+type coverageSpan struct {
+	name       string
+	start, end istanbulPosition
+	count      int
+}
+
+func (s coverageSpan) location() istanbulLocation {
+	return istanbulLocation{s.start, s.end}
+}
+
+// report returns the coverage of the script by line, function and block,
+// or nil if its source is unknown.
+func (c *Coverage) report(s ScriptCoverage) *coverageReport {
+	src, ok := c.sources[s.URL]
+	if !ok || s.URL == "" {
+		return nil
+	}
+	text := utf16.Encode([]rune(src))
+	var starts []int // the offsets of the lines
+	starts = append(starts, 0)
+	for i, u := range text {
+		if u == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	position := func(offset int) istanbulPosition {
+		line := sort.Search(len(starts), func(i int) bool { return starts[i] > offset })
+		return istanbulPosition{Line: line, Column: offset - starts[line-1]}
+	}
+
+	var ranges []CoverageRange
+	r := &coverageReport{}
+	var anonymous int
+	for _, f := range s.Functions {
+		ranges = append(ranges, f.Ranges...)
+		if len(f.Ranges) == 0 {
+			continue
+		}
+		// The script itself is not a function.
+		fr := f.Ranges[0]
+		if f.FunctionName != "" || fr.StartOffset > 0 || fr.EndOffset < len(text) {
+			name := f.FunctionName
+			if name == "" {
+				name = fmt.Sprintf("(anonymous_%d)", anonymous)
+				anonymous++
+			}
+			r.functions = append(r.functions, coverageSpan{name, position(fr.StartOffset), position(fr.EndOffset), fr.Count})
+		}
+		for _, b := range f.Ranges[1:] {
+			r.branches = append(r.branches, coverageSpan{"", position(b.StartOffset), position(b.EndOffset), b.Count})
+		}
+	}
+	// Sort the ranges so that they follow the ranges that contain them.
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].StartOffset != ranges[j].StartOffset {
+			return ranges[i].StartOffset < ranges[j].StartOffset
+		}
+		return ranges[i].EndOffset > ranges[j].EndOffset
+	})
+
+	// Each line that isn't blank is counted by the innermost range that
+	// contains it.
+	for i, start := range starts {
+		end := len(text)
+		if i+1 < len(starts) {
+			end = starts[i+1] - 1
+		}
+		for start < end && isSpace(text[start]) {
+			start++
+		}
+		for end > start && isSpace(text[end-1]) {
+			end--
+		}
+		if start == end {
+			continue
+		}
+		count := -1
+		for _, rg := range ranges {
+			if rg.StartOffset <= start && end <= rg.EndOffset {
+				count = rg.Count
+			}
+		}
+		if count < 0 {
+			continue
+		}
+		r.lines = append(r.lines, coverageSpan{"", position(start), position(end), count})
+	}
+	return r
+}
+
+func isSpace(u uint16) bool {
+	return u == ' ' || u == '\t' || u == '\r' || u == '\n'
+}
@@ -0,0 +1,147 @@
+// Copyright 2023 the v8go contributors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package v8go_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	v8 "rogchap.com/v8go"
+)
+
+const coverageScript = `function rule(x) {
+  if (x > 0) {
+    return "positive";
+  }
+  return "negative";
+}
+function unused() {
+  return 0;
+}
+rule(1);
+rule(2);`
+
+func TestIsolateCoverage(t *testing.T) {
+	t.Parallel()
+
+	iso := v8.NewIsolate()
+	defer iso.Dispose()
+	ctx := v8.NewContext(iso)
+	defer ctx.Close()
+
+	if _, err := iso.TakeCoverage(); err == nil {
+		t.Error("expected an error before coverage is started")
+	}
+	fatalIf(t, iso.StartCoverage(v8.CoverageBlock))
+	if err := iso.StartCoverage(v8.CoverageBlock); err == nil {
+		t.Error("expected an error when coverage is already started")
+	}
+
+	_, err := ctx.RunScript(coverageScript, "rules.js")
+	fatalIf(t, err)
+	cov, err := iso.TakeCoverage()
+	fatalIf(t, err)
+
+	var script *v8.ScriptCoverage
+	for i := range cov.Scripts {
+		if cov.Scripts[i].URL == "rules.js" {
+			script = &cov.Scripts[i]
+		}
+	}
+	if script == nil {
+		t.Fatalf("expected coverage of rules.js: %+v", cov.Scripts)
+	}
+	counts := make(map[string]int)
+	for _, f := range script.Functions {
+		counts[f.FunctionName] = f.Ranges[0].Count
+	}
+	if counts["rule"] != 2 || counts["unused"] != 0 {
+		t.Errorf("unexpected function counts %v", counts)
+	}
+
+	var lcov bytes.Buffer
+	fatalIf(t, cov.WriteLCOV(&lcov))
+	for _, s := range []string{"SF:rules.js\n", "FNDA:2,rule\n", "FNDA:0,unused\n", "DA:5,0\n", "DA:8,0\n", "DA:3,2\n", "end_of_record\n"} {
+		if !strings.Contains(lcov.String(), s) {
+			t.Errorf("expected %q in LCOV:\n%s", s, lcov.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	fatalIf(t, cov.WriteIstanbul(&buf))
+	var istanbul map[string]struct {
+		Path  string `json:"path"`
+		FnMap map[string]struct {
+			Name string `json:"name"`
+		} `json:"fnMap"`
+		F map[string]int `json:"f"`
+	}
+	fatalIf(t, json.Unmarshal(buf.Bytes(), &istanbul))
+	file, ok := istanbul["rules.js"]
+	if !ok || file.Path != "rules.js" {
+		t.Fatalf("expected rules.js in Istanbul JSON: %s", buf.String())
+	}
+	for key, fn := range file.FnMap {
+		if fn.Name == "rule" && file.F[key] != 2 {
+			t.Errorf("expected rule to be called twice, got %d", file.F[key])
+		}
+	}
+
+	// the counts are reset when taken
+	cov, err = iso.TakeCoverage()
+	fatalIf(t, err)
+	for _, s := range cov.Scripts {
+		for _, f := range s.Functions {
+			if f.FunctionName == "rule" && f.Ranges[0].Count != 0 {
+				t.Errorf("expected the count of rule to be reset, got %d", f.Ranges[0].Count)
+			}
+		}
+	}
+
+	fatalIf(t, iso.StopCoverage())
+	if err := iso.StopCoverage(); err == nil {
+		t.Error("expected an error when coverage is stopped")
+	}
+}
+
+func TestIsolateCoverageConsoleDelegate(t *testing.T) {
+	t.Parallel()
+
+	iso := v8.NewIsolate()
+	defer iso.Dispose()
+	ctx := v8.NewContext(iso)
+	defer ctx.Close()
+
+	var texts []string
+	iso.SetConsoleDelegate(v8.ConsoleDelegateFunc(func(c *v8.Context, msg *v8.ConsoleMessage) {
+		texts = append(texts, msg.String())
+	}))
+	fatalIf(t, iso.StartCoverage(v8.CoverageBestEffort))
+	_, err := ctx.RunScript(`console.log("covered")`, "log.js")
+	fatalIf(t, err)
+	fatalIf(t, iso.StopCoverage())
+
+	if len(texts) != 1 || texts[0] != "covered" {
+		t.Errorf("expected the console delegate to be kept, got %q", texts)
+	}
+}
+
+func TestIsolateCoverageInspectorDisposed(t *testing.T) {
+	t.Parallel()
+
+	iso := v8.NewIsolate()
+	defer iso.Dispose()
+
+	fatalIf(t, iso.StartCoverage(v8.CoverageCount))
+	iso.Inspector().Dispose()
+	if _, err := iso.TakeCoverage(); err == nil {
+		t.Error("expected an error taking coverage after the inspector is disposed")
+	}
+	if err := iso.StopCoverage(); err == nil {
+		t.Error("expected an error stopping coverage after the inspector is disposed")
+	}
+}
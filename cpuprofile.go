@@ -8,7 +8,12 @@ package v8go
 #include "v8go.h"
 */
 import "C"
-import "time"
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
 
 type CPUProfile struct {
 	p *C.CPUProfile
@@ -27,6 +32,18 @@ type CPUProfile struct {
 	// since some unspecified starting point.
 	// The point is equal to the starting point used by startTimeOffset.
 	endTimeOffset time.Duration
+
+	// samples are the samples of the profile, in the order they were taken.
+	samples []CPUProfileSample
+}
+
+// CPUProfileSample is a sample of a CPUProfile: the node of the call tree
+// that was executing when the sample was taken.
+type CPUProfileSample struct {
+	NodeID int
+	// Timestamp is the time of the sample since the same unspecified starting
+	// point as the start and end times of the profile.
+	Timestamp time.Duration
 }
 
 // Returns CPU profile title.
@@ -44,6 +61,14 @@ func (c *CPUProfile) GetDuration() time.Duration {
 	return c.endTimeOffset - c.startTimeOffset
 }
 
+// Samples returns the samples of the profile in the order they were taken,
+// which can be used to show the call stacks over time. Samples are only
+// recorded by profiles started with CPUProfiler.StartProfilingWithOptions,
+// since they take memory for the whole duration of the profile.
+func (c *CPUProfile) Samples() []CPUProfileSample {
+	return c.samples
+}
+
 // Deletes the profile and removes it from CpuProfiler's list.
 // All pointers to nodes previously returned become invalid.
 func (c *CPUProfile) Delete() {
@@ -53,3 +78,120 @@ func (c *CPUProfile) Delete() {
 	C.CPUProfileDelete(c.p)
 	c.p = nil
 }
+
+// WritePprof writes the profile in the gzipped protocol buffer format of
+// pprof, so that it can be analyzed with `go tool pprof`. Each node of the
+// call tree that was sampled is a sample, with its stack of JavaScript
+// functions and the count and estimated CPU time of its samples.
+func (c *CPUProfile) WritePprof(w io.Writer) error {
+	var hits int64
+	c.root.walk(func(n *CPUProfileNode) {
+		hits += int64(n.hitCount)
+	})
+	var period int64
+	if hits > 0 {
+		period = int64(c.GetDuration()) / hits
+	}
+
+	b := newProfileBuilder()
+	b.sampleTypes([2]string{"samples", "count"}, [2]string{"cpu", "nanoseconds"})
+	b.period("cpu", "nanoseconds", period)
+	b.timing(time.Time{}, c.GetDuration())
+
+	c.root.walk(func(n *CPUProfileNode) {
+		if n.hitCount == 0 {
+			return
+		}
+		var stack []uint64
+		for f := n; f.parent != nil; f = f.parent {
+			fn := pprofFunction{
+				name:     f.functionName,
+				filename: f.scriptResourceName,
+				line:     int64(f.lineNumber),
+			}
+			if fn.name == "" {
+				fn.name = "(anonymous)"
+			}
+			stack = append(stack, b.location(fn, int64(f.lineNumber), int64(f.columnNumber)))
+		}
+		if len(stack) == 0 {
+			return
+		}
+		b.sample(stack, []int64{int64(n.hitCount), int64(n.hitCount) * period}, nil)
+	})
+	return b.write(w)
+}
+
+type chromeProfile struct {
+	Nodes      []chromeProfileNode `json:"nodes"`
+	StartTime  int64               `json:"startTime"`
+	EndTime    int64               `json:"endTime"`
+	Samples    []int               `json:"samples"`
+	TimeDeltas []int64             `json:"timeDeltas"`
+}
+
+type chromeProfileNode struct {
+	ID            int                  `json:"id"`
+	CallFrame     chromeCallFrame      `json:"callFrame"`
+	HitCount      int                  `json:"hitCount"`
+	Children      []int                `json:"children,omitempty"`
+	PositionTicks []chromePositionTick `json:"positionTicks,omitempty"`
+}
+
+// chromePositionTick is a Profiler.PositionTickInfo, whose line is 1-based.
+type chromePositionTick struct {
+	Line  int `json:"line"`
+	Ticks int `json:"ticks"`
+}
+
+// chromeCallFrame is a Runtime.CallFrame of the DevTools protocol, whose line
+// and column numbers are 0-based.
+type chromeCallFrame struct {
+	FunctionName string `json:"functionName"`
+	ScriptID     string `json:"scriptId"`
+	URL          string `json:"url"`
+	LineNumber   int    `json:"lineNumber"`
+	ColumnNumber int    `json:"columnNumber"`
+}
+
+// WriteChromeProfile writes the profile as JSON in the .cpuprofile format of
+// Chrome DevTools, the Profiler.Profile type of the DevTools protocol, which
+// can be loaded in the Performance panel to show flame charts over time,
+// which requires the samples of the profile, see Samples.
+// Times are in microseconds.
+func (c *CPUProfile) WriteChromeProfile(w io.Writer) error {
+	p := chromeProfile{
+		StartTime:  c.startTimeOffset.Microseconds(),
+		EndTime:    c.endTimeOffset.Microseconds(),
+		Samples:    make([]int, len(c.samples)),
+		TimeDeltas: make([]int64, len(c.samples)),
+	}
+	c.root.walk(func(n *CPUProfileNode) {
+		node := chromeProfileNode{
+			ID: n.nodeId,
+			CallFrame: chromeCallFrame{
+				FunctionName: n.functionName,
+				ScriptID:     strconv.Itoa(n.scriptId),
+				URL:          n.scriptResourceName,
+				LineNumber:   n.lineNumber - 1,
+				ColumnNumber: n.columnNumber - 1,
+			},
+			HitCount: n.hitCount,
+		}
+		for _, child := range n.children {
+			node.Children = append(node.Children, child.nodeId)
+		}
+		for _, tick := range n.lineTicks {
+			node.PositionTicks = append(node.PositionTicks, chromePositionTick{tick.Line, tick.HitCount})
+		}
+		p.Nodes = append(p.Nodes, node)
+	})
+
+	last := c.startTimeOffset
+	for i, s := range c.samples {
+		p.Samples[i] = s.NodeID
+		p.TimeDeltas[i] = (s.Timestamp - last).Microseconds()
+		last = s.Timestamp
+	}
+	return json.NewEncoder(w).Encode(p)
+}
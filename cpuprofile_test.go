@@ -5,6 +5,8 @@
 package v8go_test
 
 import (
+	"bytes"
+	"encoding/json"
 	"testing"
 
 	v8 "rogchap.com/v8go"
@@ -68,3 +70,115 @@ func TestCPUProfile_Delete(t *testing.T) {
 	// noop when called multiple times
 	cpuProfile.Delete()
 }
+
+func TestCPUProfile_WritePprof(t *testing.T) {
+	t.Parallel()
+
+	ctx := v8.NewContext(nil)
+	iso := ctx.Isolate()
+	defer iso.Dispose()
+	defer ctx.Close()
+
+	cpuProfiler := v8.NewCPUProfiler(iso)
+	defer cpuProfiler.Dispose()
+
+	cpuProfiler.StartProfiling("pprof")
+	_, err := ctx.RunScript(profileScript, "script.js")
+	fatalIf(t, err)
+	_, err = ctx.RunScript("start()", "")
+	fatalIf(t, err)
+	cpuProfile := cpuProfiler.StopProfiling("pprof")
+	defer cpuProfile.Delete()
+
+	var buf bytes.Buffer
+	fatalIf(t, cpuProfile.WritePprof(&buf))
+
+	pprof := decodePprof(t, &buf)
+	if len(pprof.samples) == 0 || len(pprof.locations) == 0 || pprof.functions == 0 {
+		t.Errorf("expected samples, locations and functions, got %d, %d and %d", len(pprof.samples), len(pprof.locations), pprof.functions)
+	}
+	for _, locs := range pprof.samples {
+		for _, id := range locs {
+			if !pprof.locations[id] {
+				t.Errorf("sample with unknown location %d", id)
+			}
+		}
+	}
+	// the string table holds the names of the sample types and functions
+	for _, s := range []string{"samples", "cpu", "nanoseconds", "loop", "script.js"} {
+		if !pprof.hasString(s) {
+			t.Errorf("expected %q in the profile", s)
+		}
+	}
+}
+
+func TestCPUProfile_WriteChromeProfile(t *testing.T) {
+	t.Parallel()
+
+	ctx := v8.NewContext(nil)
+	iso := ctx.Isolate()
+	defer iso.Dispose()
+	defer ctx.Close()
+
+	cpuProfiler := v8.NewCPUProfiler(iso)
+	defer cpuProfiler.Dispose()
+
+	fatalIf(t, cpuProfiler.StartProfilingWithOptions("chrome", v8.CPUProfilerOptions{}))
+	_, err := ctx.RunScript(profileScript, "script.js")
+	fatalIf(t, err)
+	_, err = ctx.RunScript("start()", "")
+	fatalIf(t, err)
+	cpuProfile := cpuProfiler.StopProfiling("chrome")
+	defer cpuProfile.Delete()
+
+	samples := cpuProfile.Samples()
+	if len(samples) == 0 {
+		t.Fatal("expected samples")
+	}
+	for i := 1; i < len(samples); i++ {
+		if samples[i].Timestamp < samples[i-1].Timestamp {
+			t.Fatalf("samples out of order: %v", samples)
+		}
+	}
+
+	var buf bytes.Buffer
+	fatalIf(t, cpuProfile.WriteChromeProfile(&buf))
+
+	var profile struct {
+		Nodes []struct {
+			ID        int `json:"id"`
+			CallFrame struct {
+				FunctionName string `json:"functionName"`
+			} `json:"callFrame"`
+		} `json:"nodes"`
+		StartTime  int64   `json:"startTime"`
+		EndTime    int64   `json:"endTime"`
+		Samples    []int   `json:"samples"`
+		TimeDeltas []int64 `json:"timeDeltas"`
+	}
+	fatalIf(t, json.Unmarshal(buf.Bytes(), &profile))
+
+	if len(profile.Nodes) == 0 || profile.Nodes[0].CallFrame.FunctionName != "(root)" {
+		t.Fatalf("unexpected nodes: %+v", profile.Nodes)
+	}
+	if profile.EndTime-profile.StartTime != cpuProfile.GetDuration().Microseconds() {
+		t.Errorf("unexpected times %d-%d", profile.StartTime, profile.EndTime)
+	}
+	if len(profile.Samples) != len(samples) || len(profile.TimeDeltas) != len(samples) {
+		t.Fatalf("expected %d samples, got %d with %d deltas", len(samples), len(profile.Samples), len(profile.TimeDeltas))
+	}
+	ids := make(map[int]bool)
+	for _, n := range profile.Nodes {
+		ids[n.ID] = true
+	}
+	ts := profile.StartTime
+	for i, id := range profile.Samples {
+		if !ids[id] {
+			t.Errorf("sample %d references unknown node %d", i, id)
+		}
+		ts += profile.TimeDeltas[i]
+	}
+	if last := samples[len(samples)-1].Timestamp.Microseconds(); ts != last {
+		t.Errorf("expected deltas to add up to %d, got %d", last, ts)
+	}
+}
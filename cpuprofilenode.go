@@ -29,6 +29,9 @@ type CPUProfileNode struct {
 	// The bailout reason for the function if the optimization was disabled for it.
 	bailoutReason string
 
+	// The hit counts of the lines of the function.
+	lineTicks []CPUProfileLineTick
+
 	// The children node of this node.
 	children []*CPUProfileNode
 
@@ -76,6 +79,22 @@ func (c *CPUProfileNode) GetBailoutReason() string {
 	return c.bailoutReason
 }
 
+// CPUProfileLineTick is the number of samples taken on a line of the function
+// of a CPUProfileNode.
+type CPUProfileLineTick struct {
+	// The 1-based number of the line.
+	Line int
+
+	// The count of samples on the line.
+	HitCount int
+}
+
+// Returns the count of samples on each line of the function, to find the
+// expensive lines of a hot function.
+func (c *CPUProfileNode) GetLineTicks() []CPUProfileLineTick {
+	return c.lineTicks
+}
+
 // Retrieves the ancestor node, or nil if the root.
 func (c *CPUProfileNode) GetParent() *CPUProfileNode {
 	return c.parent
@@ -89,3 +108,11 @@ func (c *CPUProfileNode) GetChildrenCount() int {
 func (c *CPUProfileNode) GetChild(index int) *CPUProfileNode {
 	return c.children[index]
 }
+
+// walk calls fn for the node and its descendants, depth first.
+func (c *CPUProfileNode) walk(fn func(*CPUProfileNode)) {
+	fn(c)
+	for _, child := range c.children {
+		child.walk(fn)
+	}
+}
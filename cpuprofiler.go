@@ -10,6 +10,7 @@ package v8go
 */
 import "C"
 import (
+	"errors"
 	"time"
 	"unsafe"
 )
@@ -52,6 +53,77 @@ func (c *CPUProfiler) StartProfiling(title string) {
 	C.CPUProfilerStartProfiling(c.p, tstr)
 }
 
+// CPUProfilingMode selects how the line numbers of the samples of a CPU
+// profile are attributed.
+type CPUProfilingMode int
+
+const (
+	// LeafNodeLineNumbers records the line numbers of the leaf nodes only:
+	// the call tree has one node per function per caller, and
+	// CPUProfileNode.GetLineTicks tells the lines of the function that ran.
+	LeafNodeLineNumbers CPUProfilingMode = iota
+	// CallerLineNumbers also records the line numbers of the callers: the
+	// call tree has one node per call site.
+	CallerLineNumbers
+)
+
+// CPUProfilerOptions configures a profile started with
+// CPUProfiler.StartProfilingWithOptions.
+type CPUProfilerOptions struct {
+	// SamplingInterval is the interval between samples, rounded to a multiple
+	// of the sampling interval of the profiler. Zero uses the profiler's.
+	SamplingInterval time.Duration
+
+	// Mode selects how the line numbers of the samples are recorded.
+	Mode CPUProfilingMode
+
+	// MaxSamples is the number of samples after which the profile stops
+	// recording samples; the call tree is still updated. Zero means no limit.
+	MaxSamples int
+
+	// NoSamples disables recording the individual samples returned by
+	// CPUProfile.Samples, keeping only the call tree.
+	NoSamples bool
+
+	// FilterContext, if not nil, restricts the samples to the JavaScript
+	// that runs in the Context.
+	FilterContext *Context
+}
+
+// ErrTooManyProfiles is returned when a profile cannot be started because
+// too many profiles are already being collected.
+var ErrTooManyProfiles = errors.New("v8go: too many CPU profiles are being collected")
+
+// StartProfilingWithOptions starts collecting a CPU profile with the given
+// options, see StartProfiling.
+func (c *CPUProfiler) StartProfilingWithOptions(title string, opts CPUProfilerOptions) error {
+	if c.p == nil || c.iso.ptr == nil {
+		panic("profiler or isolate are nil")
+	}
+
+	tstr := C.CString(title)
+	defer C.free(unsafe.Pointer(tstr))
+
+	copts := C.CPUProfilingOptions{
+		mode:               C.int(opts.Mode),
+		maxSamples:         C.uint(opts.MaxSamples),
+		samplingIntervalUs: C.int(opts.SamplingInterval / time.Microsecond),
+	}
+	switch {
+	case opts.NoSamples:
+		copts.maxSamples = 0
+	case opts.MaxSamples <= 0:
+		copts.maxSamples = ^C.uint(0) // v8::CpuProfilingOptions::kNoSampleLimit
+	}
+	if opts.FilterContext != nil {
+		copts.filterContext = opts.FilterContext.ptr
+	}
+	if C.CPUProfilerStartProfilingWithOptions(c.p, tstr, copts) == 0 {
+		return ErrTooManyProfiles
+	}
+	return nil
+}
+
 // Stops collecting CPU profile with a given title and returns it.
 // If the title given is empty, finishes the last profile started.
 func (c *CPUProfiler) StopProfiling(title string) *CPUProfile {
@@ -64,13 +136,25 @@ func (c *CPUProfiler) StopProfiling(title string) *CPUProfile {
 
 	profile := C.CPUProfilerStopProfiling(c.p, tstr)
 
-	return &CPUProfile{
+	p := &CPUProfile{
 		p:               profile,
 		title:           C.GoString(profile.title),
 		root:            newCPUProfileNode(profile.root, nil),
-		startTimeOffset: time.Duration(profile.startTime) * time.Millisecond,
-		endTimeOffset:   time.Duration(profile.endTime) * time.Millisecond,
+		startTimeOffset: time.Duration(profile.startTime) * time.Microsecond,
+		endTimeOffset:   time.Duration(profile.endTime) * time.Microsecond,
+	}
+	if n := int(profile.samplesCount); n > 0 {
+		nodeIds := unsafe.Slice(profile.sampleNodeIds, n)
+		timestamps := unsafe.Slice(profile.sampleTimestamps, n)
+		p.samples = make([]CPUProfileSample, n)
+		for i := range p.samples {
+			p.samples[i] = CPUProfileSample{
+				NodeID:    int(nodeIds[i]),
+				Timestamp: time.Duration(timestamps[i]) * time.Microsecond,
+			}
+		}
 	}
+	return p
 }
 
 func newCPUProfileNode(node *C.CPUProfileNode, parent *CPUProfileNode) *CPUProfileNode {
@@ -86,6 +170,16 @@ func newCPUProfileNode(node *C.CPUProfileNode, parent *CPUProfileNode) *CPUProfi
 		parent:             parent,
 	}
 
+	if count := int(node.lineTicksCount); count > 0 {
+		n.lineTicks = make([]CPUProfileLineTick, count)
+		for i, tick := range unsafe.Slice(node.lineTicks, count) {
+			n.lineTicks[i] = CPUProfileLineTick{
+				Line:     int(tick.line),
+				HitCount: int(tick.hitCount),
+			}
+		}
+	}
+
 	if node.childrenCount > 0 {
 		n.children = make([]*CPUProfileNode, node.childrenCount)
 		for i, child := range (*[1 << 28]*C.CPUProfileNode)(unsafe.Pointer(node.children))[:node.childrenCount:node.childrenCount] {
@@ -6,6 +6,7 @@ package v8go_test
 
 import (
 	"testing"
+	"time"
 
 	v8 "rogchap.com/v8go"
 )
@@ -77,6 +78,67 @@ func TestCPUProfiler(t *testing.T) {
 	}
 }
 
+func TestCPUProfiler_StartProfilingWithOptions(t *testing.T) {
+	t.Parallel()
+
+	ctx := v8.NewContext(nil)
+	iso := ctx.Isolate()
+	defer iso.Dispose()
+	defer ctx.Close()
+
+	cpuProfiler := v8.NewCPUProfiler(iso)
+	defer cpuProfiler.Dispose()
+
+	fatalIf(t, cpuProfiler.StartProfilingWithOptions("options", v8.CPUProfilerOptions{
+		SamplingInterval: 100 * time.Microsecond,
+		MaxSamples:       5,
+		FilterContext:    ctx,
+	}))
+	_, err := ctx.RunScript(profileScript, "script.js")
+	fatalIf(t, err)
+	_, err = ctx.RunScript("start(50)", "")
+	fatalIf(t, err)
+	cpuProfile := cpuProfiler.StopProfiling("options")
+	defer cpuProfile.Delete()
+
+	if n := len(cpuProfile.Samples()); n == 0 || n > 5 {
+		t.Errorf("expected between 1 and 5 samples, got %d", n)
+	}
+
+	// The samples in loop are attributed to the lines of its body.
+	var ticks []v8.CPUProfileLineTick
+	var walk func(*v8.CPUProfileNode)
+	walk = func(n *v8.CPUProfileNode) {
+		if n.GetFunctionName() == "loop" {
+			ticks = append(ticks, n.GetLineTicks()...)
+		}
+		for i := 0; i < n.GetChildrenCount(); i++ {
+			walk(n.GetChild(i))
+		}
+	}
+	walk(cpuProfile.GetTopDownRoot())
+	if len(ticks) == 0 {
+		t.Fatal("expected line ticks for loop")
+	}
+	for _, tick := range ticks {
+		if tick.Line < 1 || tick.Line > 11 || tick.HitCount <= 0 {
+			t.Errorf("unexpected line tick %+v", tick)
+		}
+	}
+
+	fatalIf(t, cpuProfiler.StartProfilingWithOptions("nosamples", v8.CPUProfilerOptions{
+		Mode:      v8.CallerLineNumbers,
+		NoSamples: true,
+	}))
+	_, err = ctx.RunScript("start(10)", "")
+	fatalIf(t, err)
+	cpuProfile = cpuProfiler.StopProfiling("nosamples")
+	defer cpuProfile.Delete()
+	if n := len(cpuProfile.Samples()); n != 0 {
+		t.Errorf("expected no samples, got %d", n)
+	}
+}
+
 const profileScript = `function loop(timeout) {
   this.mmm = 0;
   var start = Date.now();
@@ -8,11 +8,21 @@ package v8go
 // #include "v8go.h"
 import "C"
 import (
+	"errors"
 	"fmt"
 	"io"
 	"unsafe"
 )
 
+// ErrExecutionTerminated matches, with errors.Is, the error returned when
+// execution was terminated with Isolate.TerminateExecution or because the
+// context.Context of a call such as Context.RunScriptContext was done.
+var ErrExecutionTerminated = errors.New("v8go: execution terminated")
+
+// ErrHeapLimitExceeded is returned when execution was terminated because the
+// Isolate ran out of heap memory. See Isolate.SetNearHeapLimitCallback.
+var ErrHeapLimitExceeded = errors.New("v8go: heap limit exceeded")
+
 // JSError is an error that is returned if there is are any
 // JavaScript exceptions handled in the context. When used with the fmt
 // verb `%+v`, will output the JavaScript stack trace, if available.
@@ -20,24 +30,180 @@ type JSError struct {
 	Message    string
 	Location   string
 	StackTrace string
+
+	// Name is the name of the exception, eg. "TypeError"; it is empty if the
+	// exception is not an object with a name, such as a thrown string.
+	Name string
+	// Frames are the stack frames of the exception, with the innermost first.
+	Frames []StackFrame
+
+	// Value is the thrown value, which can be inspected, thrown again or used
+	// to reject a promise; it is only valid until the Context it was thrown in
+	// is closed. It is nil if execution was terminated, or if the error was
+	// not thrown in a Context, such as by Isolate.CompileUnboundScript or
+	// Isolate.CompileModule.
+	Value *Value
+
+	terminated bool
+}
+
+// StackFrame is a frame of a JavaScript stack trace, such as of a JSError.
+type StackFrame struct {
+	// ScriptName is the origin of the script, or its `//# sourceURL`.
+	ScriptName   string
+	FunctionName string
+	// Line and Column are 1-based.
+	Line          int
+	Column        int
+	ScriptID      int
+	IsEval        bool
+	IsConstructor bool
+	IsWasm        bool
 }
 
 func newJSError(rtnErr C.RtnError) error {
+	defer freeRtnError(rtnErr)
+	if rtnErr.heapLimitExceeded == 1 {
+		return ErrHeapLimitExceeded
+	}
 	err := &JSError{
 		Message:    C.GoString(rtnErr.msg),
 		Location:   C.GoString(rtnErr.location),
 		StackTrace: C.GoString(rtnErr.stack),
+		Name:       C.GoString(rtnErr.name),
+		terminated: rtnErr.terminated == 1,
+	}
+	if rtnErr.exception != nil {
+		err.Value = &Value{ptr: rtnErr.exception, ctx: getContext(int(rtnErr.exceptionCtxRef))}
+	}
+	if rtnErr.frameCount > 0 {
+		frames := unsafe.Slice(rtnErr.frames, int(rtnErr.frameCount))
+		err.Frames = make([]StackFrame, len(frames))
+		for i, f := range frames {
+			err.Frames[i] = newStackFrame(f)
+		}
+	}
+	if iso := getIsolate(int(rtnErr.isolateRef)); iso != nil {
+		iso.applySourceMaps(err)
 	}
+	return err
+}
+
+func newStackFrame(f C.ErrorStackFrame) StackFrame {
+	return StackFrame{
+		ScriptName:    C.GoString(f.scriptName),
+		FunctionName:  C.GoString(f.functionName),
+		Line:          int(f.lineNumber),
+		Column:        int(f.columnNumber),
+		ScriptID:      int(f.scriptId),
+		IsEval:        f.isEval != 0,
+		IsConstructor: f.isConstructor != 0,
+		IsWasm:        f.isWasm != 0,
+	}
+}
+
+func freeRtnError(rtnErr C.RtnError) {
 	C.free(unsafe.Pointer(rtnErr.msg))
 	C.free(unsafe.Pointer(rtnErr.location))
 	C.free(unsafe.Pointer(rtnErr.stack))
-	return err
+	C.free(unsafe.Pointer(rtnErr.name))
+	if rtnErr.frames != nil {
+		C.ErrorStackFramesDelete(rtnErr.frames, rtnErr.frameCount)
+	}
+}
+
+// ErrorKind is the kind of JavaScript error created by NewError.
+type ErrorKind int
+
+const (
+	GenericError ErrorKind = iota // Error
+	RangeError
+	ReferenceError
+	SyntaxError
+	TypeError
+)
+
+// NewError creates a JavaScript error object of the given kind, such as a
+// TypeError. Called from a FunctionCallback, the error is created in the
+// current context so that `instanceof` works in scripts; it can be thrown
+// with Isolate.ThrowException or used to reject a promise.
+func NewError(iso *Isolate, kind ErrorKind, message string) *Object {
+	return &Object{newErrorValue(iso, kind, message, nil)}
+}
+
+// NewErrorWithCause is like NewError, setting the `cause` property of the
+// error to the given value.
+func NewErrorWithCause(iso *Isolate, kind ErrorKind, message string, cause Valuer) *Object {
+	if cause == nil {
+		panic("nil cause argument not supported")
+	}
+	return &Object{newErrorValue(iso, kind, message, cause.value())}
+}
+
+// errorValue converts err to the JS value to throw for it, see
+// NewFunctionTemplateWithError.
+func errorValue(iso *Isolate, err error) *Value {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		switch v := e.(type) {
+		case *JSError:
+			if v.Value != nil {
+				return v.Value
+			}
+		case Valuer:
+			return v.value()
+		}
+	}
+
+	var cause *Value
+	if wrapped := errors.Unwrap(err); wrapped != nil {
+		cause = errorValue(iso, wrapped)
+	}
+	return newErrorValue(iso, GenericError, err.Error(), cause)
+}
+
+func newErrorValue(iso *Isolate, kind ErrorKind, message string, cause *Value) *Value {
+	if iso == nil {
+		panic("nil Isolate argument not supported")
+	}
+	cmsg := C.CString(message)
+	defer C.free(unsafe.Pointer(cmsg))
+
+	var causePtr C.ValuePtr
+	if cause != nil {
+		causePtr = cause.ptr
+	}
+	ptr := C.NewValueError(iso.ptr, C.ErrorKind(kind), cmsg, causePtr)
+	return &Value{ptr: ptr, ctx: nil}
 }
 
 func (e *JSError) Error() string {
 	return e.Message
 }
 
+// Is reports whether the error is ErrExecutionTerminated, for errors.Is.
+func (e *JSError) Is(target error) bool {
+	return e.terminated && target == ErrExecutionTerminated
+}
+
+// terminatedError is returned when execution was terminated because a
+// context.Context was done; with errors.Is it matches ErrExecutionTerminated
+// as well as the error of the context.
+type terminatedError struct {
+	reason error
+}
+
+func (e *terminatedError) Error() string {
+	return ErrExecutionTerminated.Error() + ": " + e.reason.Error()
+}
+
+func (e *terminatedError) Is(target error) bool {
+	return target == ErrExecutionTerminated
+}
+
+func (e *terminatedError) Unwrap() error {
+	return e.reason
+}
+
 // Format implements the fmt.Formatter interface to provide a custom formatter
 // primarily to output the javascript stack trace with %+v
 func (e *JSError) Format(s fmt.State, verb rune) {
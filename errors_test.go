@@ -117,3 +117,141 @@ func TestJSErrorFormat_forSyntaxError(t *testing.T) {
 		t.Errorf("unexpected verbose error message: %q", msg)
 	}
 }
+
+func TestNewError(t *testing.T) {
+	t.Parallel()
+	iso := v8.NewIsolate()
+	defer iso.Dispose()
+	ctx := v8.NewContext(iso)
+	defer ctx.Close()
+
+	tests := [...]struct {
+		kind     v8.ErrorKind
+		expected string
+	}{
+		{v8.GenericError, "Error"},
+		{v8.RangeError, "RangeError"},
+		{v8.ReferenceError, "ReferenceError"},
+		{v8.SyntaxError, "SyntaxError"},
+		{v8.TypeError, "TypeError"},
+	}
+	for _, tt := range tests {
+		e := v8.NewError(iso, tt.kind, "oops")
+		if !e.IsNativeError() {
+			t.Errorf("expected a native error for %s", tt.expected)
+		}
+		if s := e.String(); s != tt.expected+": oops" {
+			t.Errorf("unexpected error string: %q", s)
+		}
+	}
+
+	throwFn := v8.NewFunctionTemplate(iso, func(info *v8.FunctionCallbackInfo) *v8.Value {
+		cause, _ := v8.NewValue(iso, "bad input")
+		return iso.ThrowException(v8.NewErrorWithCause(iso, v8.TypeError, "invalid argument", cause).Value)
+	})
+	fatalIf(t, ctx.Global().Set("throwTypeError", throwFn.GetFunction(ctx)))
+
+	val, err := ctx.RunScript(`
+		try { throwTypeError() } catch (e) { [e instanceof TypeError, e.message, e.cause, typeof e.stack].join() }
+	`, "")
+	fatalIf(t, err)
+	if s := val.String(); s != "true,invalid argument,bad input,string" {
+		t.Errorf("unexpected value: %q", s)
+	}
+}
+
+func TestJSErrorFrames(t *testing.T) {
+	t.Parallel()
+	iso := v8.NewIsolate()
+	defer iso.Dispose()
+	ctx := v8.NewContext(iso)
+	defer ctx.Close()
+
+	_, err := ctx.RunScript(`
+		class Point {
+			constructor(x) { this.x = x.value; }
+		}
+		function make() { return new Point(); }
+	`, "point.js")
+	fatalIf(t, err)
+	_, err = ctx.RunScript(`make()`, "main.js")
+	jsErr, ok := err.(*v8.JSError)
+	if !ok {
+		t.Fatalf("expected a JSError, got %v", err)
+	}
+	if jsErr.Name != "TypeError" {
+		t.Errorf("unexpected name: %q", jsErr.Name)
+	}
+	expected := []v8.StackFrame{
+		{ScriptName: "point.js", FunctionName: "Point", Line: 3, IsConstructor: true},
+		{ScriptName: "point.js", FunctionName: "make", Line: 5, Column: 28},
+		{ScriptName: "main.js", Line: 1, Column: 1},
+	}
+	if len(jsErr.Frames) != len(expected) {
+		t.Fatalf("unexpected frames: %+v", jsErr.Frames)
+	}
+	for i, f := range jsErr.Frames {
+		if f.ScriptID == 0 || f.Column == 0 {
+			t.Errorf("expected a script ID and column for frame %d", i)
+		}
+		f.ScriptID = 0
+		if i == 0 {
+			f.Column = 0
+		}
+		if f != expected[i] {
+			t.Errorf("unexpected frame %d: %+v", i, f)
+		}
+	}
+
+	_, err = ctx.RunScript(`eval("null.foo")`, "eval.js")
+	jsErr = err.(*v8.JSError)
+	if len(jsErr.Frames) == 0 || !jsErr.Frames[0].IsEval {
+		t.Errorf("expected an eval frame: %+v", jsErr.Frames)
+	}
+}
+
+func TestJSErrorValue(t *testing.T) {
+	t.Parallel()
+	iso := v8.NewIsolate()
+	defer iso.Dispose()
+	ctx := v8.NewContext(iso)
+	defer ctx.Close()
+
+	_, err := ctx.RunScript(`throw {code: 42, retry: true}`, "throw.js")
+	jsErr, ok := err.(*v8.JSError)
+	if !ok || jsErr.Value == nil {
+		t.Fatalf("expected a JSError with a value, got %v", err)
+	}
+	obj, err := jsErr.Value.AsObject()
+	fatalIf(t, err)
+	code, err := obj.Get("code")
+	fatalIf(t, err)
+	if code.Int32() != 42 {
+		t.Errorf("unexpected code: %v", code)
+	}
+
+	// the same value is thrown again when returned from a callback
+	rethrow := v8.NewFunctionTemplateWithError(iso, func(info *v8.FunctionCallbackInfo) (*v8.Value, error) {
+		return nil, fmt.Errorf("rethrow: %w", jsErr)
+	})
+	fatalIf(t, ctx.Global().Set("rethrow", rethrow.GetFunction(ctx)))
+	fatalIf(t, ctx.Global().Set("original", jsErr.Value))
+	val, err := ctx.RunScript(`try { rethrow() } catch (e) { e === original && e.retry }`, "")
+	fatalIf(t, err)
+	if !val.IsTrue() {
+		t.Errorf("expected the original value to be thrown, got %v", val)
+	}
+
+	resolver, err := v8.NewPromiseResolver(ctx)
+	fatalIf(t, err)
+	resolver.Reject(jsErr.Value)
+	if r := resolver.GetPromise().Result(); !r.SameValue(jsErr.Value) {
+		t.Errorf("unexpected rejection: %v", r)
+	}
+
+	// errors thrown outside of a context are not kept
+	_, err = iso.CompileUnboundScript(`let`, "syntax.js", v8.CompileOptions{})
+	if jsErr, ok := err.(*v8.JSError); !ok || jsErr.Value != nil {
+		t.Errorf("expected a JSError without a value, got %#v", err)
+	}
+}
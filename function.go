@@ -7,6 +7,7 @@ package v8go
 // #include "v8go.h"
 import "C"
 import (
+	"context"
 	"unsafe"
 )
 
@@ -29,6 +30,14 @@ func (fn *Function) Call(recv Valuer, args ...Valuer) (*Value, error) {
 	return valueResult(fn.ctx, rtn)
 }
 
+// CallContext is like Call, but terminates the execution when ctx is cancelled
+// or its deadline is exceeded; the returned error then wraps ctx.Err().
+func (fn *Function) CallContext(ctx context.Context, recv Valuer, args ...Valuer) (*Value, error) {
+	return fn.ctx.iso.runWithContext(ctx, func() (*Value, error) {
+		return fn.Call(recv, args...)
+	})
+}
+
 // Invoke a constructor function to create an object instance.
 func (fn *Function) NewInstance(args ...Valuer) (*Object, error) {
 	var argptr *C.ValuePtr
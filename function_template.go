@@ -15,11 +15,17 @@ import (
 // FunctionCallback is a callback that is executed in Go when a function is executed in JS.
 type FunctionCallback func(info *FunctionCallbackInfo) *Value
 
+// FunctionCallbackWithError is a callback that is executed in Go when a function
+// is executed in JS; a returned error is thrown as a JS exception.
+// See NewFunctionTemplateWithError.
+type FunctionCallbackWithError func(info *FunctionCallbackInfo) (*Value, error)
+
 // FunctionCallbackInfo is the argument that is passed to a FunctionCallback.
 type FunctionCallbackInfo struct {
-	ctx  *Context
-	args []*Value
-	this *Object
+	ctx       *Context
+	args      []*Value
+	this      *Object
+	newTarget *Value
 }
 
 // Context is the current context that the callback is being executed in.
@@ -37,11 +43,29 @@ func (i *FunctionCallbackInfo) Args() []*Value {
 	return i.args
 }
 
+// IsConstructCall returns true if the function was called as a constructor,
+// eg. with `new`.
+func (i *FunctionCallbackInfo) IsConstructCall() bool {
+	return i.newTarget != nil
+}
+
+// NewTarget returns the value of `new.target`, which is the constructor that
+// `new` was called with, or undefined if this is not a construct call.
+func (i *FunctionCallbackInfo) NewTarget() *Value {
+	if i.newTarget == nil {
+		return Undefined(i.ctx.iso)
+	}
+	return i.newTarget
+}
+
 func (i *FunctionCallbackInfo) Release() {
 	for _, arg := range i.args {
 		arg.Release()
 	}
 	i.this.Release()
+	if i.newTarget != nil {
+		i.newTarget.Release()
+	}
 }
 
 // FunctionTemplate is used to create functions at runtime.
@@ -70,6 +94,72 @@ func NewFunctionTemplate(iso *Isolate, callback FunctionCallback) *FunctionTempl
 	return &FunctionTemplate{tmpl}
 }
 
+// NewFunctionTemplateWithError creates a FunctionTemplate for a given callback
+// that can return an error, which is thrown as a JS exception:
+//   - if the error is, or wraps, a *JSError, its thrown value is thrown again;
+//   - if the error is, or wraps, an error that is also a Valuer (eg. a type
+//     embedding *Value), that value is thrown;
+//   - otherwise a JS Error is thrown with the message of the error; if the
+//     error wraps another error, that is converted in the same way and set
+//     as the `cause` of the JS Error.
+func NewFunctionTemplateWithError(iso *Isolate, callback FunctionCallbackWithError) *FunctionTemplate {
+	if callback == nil {
+		panic("nil FunctionCallbackWithError argument not supported")
+	}
+	return NewFunctionTemplate(iso, func(info *FunctionCallbackInfo) *Value {
+		val, err := callback(info)
+		if err != nil {
+			return info.ctx.iso.ThrowException(errorValue(info.ctx.iso, err))
+		}
+		return val
+	})
+}
+
+// InstanceTemplate returns the ObjectTemplate of the objects created when the
+// function is called as a constructor, eg. to set their internal field count.
+func (tmpl *FunctionTemplate) InstanceTemplate() *ObjectTemplate {
+	t := &template{
+		ptr: C.FunctionTemplateInstanceTemplate(tmpl.ptr),
+		iso: tmpl.iso,
+	}
+	runtime.KeepAlive(tmpl)
+	runtime.SetFinalizer(t, (*template).finalizer)
+	return &ObjectTemplate{t}
+}
+
+// PrototypeTemplate returns the ObjectTemplate of the prototype object of the
+// function; properties set on it are shared by all instances.
+func (tmpl *FunctionTemplate) PrototypeTemplate() *ObjectTemplate {
+	t := &template{
+		ptr: C.FunctionTemplatePrototypeTemplate(tmpl.ptr),
+		iso: tmpl.iso,
+	}
+	runtime.KeepAlive(tmpl)
+	runtime.SetFinalizer(t, (*template).finalizer)
+	return &ObjectTemplate{t}
+}
+
+// Inherit causes the function to inherit from the parent function template,
+// so that its prototype inherits from the prototype of the parent and
+// `instanceof` is true for both. It must be called before GetFunction.
+func (tmpl *FunctionTemplate) Inherit(parent *FunctionTemplate) {
+	if parent == nil {
+		panic("nil FunctionTemplate argument not supported")
+	}
+	C.FunctionTemplateInherit(tmpl.ptr, parent.ptr)
+	runtime.KeepAlive(tmpl)
+	runtime.KeepAlive(parent)
+}
+
+// SetClassName sets the name of the function, which is also used as the
+// class name of its instances, eg. by `Object.prototype.toString`.
+func (tmpl *FunctionTemplate) SetClassName(name string) {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	C.FunctionTemplateSetClassName(tmpl.ptr, cname)
+	runtime.KeepAlive(tmpl)
+}
+
 // GetFunction returns an instance of this function template bound to the given context.
 func (tmpl *FunctionTemplate) GetFunction(ctx *Context) *Function {
 	rtn := C.FunctionTemplateGetFunction(tmpl.ptr, ctx.ptr)
@@ -81,21 +171,25 @@ func (tmpl *FunctionTemplate) GetFunction(ctx *Context) *Function {
 	return &Function{val}
 }
 
-// Note that ideally `thisAndArgs` would be split into two separate arguments, but they were combined
+// Note that ideally `thisAndArgs` would be split into separate arguments, but they were combined
 // to workaround an ERROR_COMMITMENT_LIMIT error on windows that was detected in CI.
+// It holds `this`, `new.target` (nil unless this is a construct call) and then the args.
 //
 //export goFunctionCallback
 func goFunctionCallback(ctxref int, cbref int, thisAndArgs *C.ValuePtr, argsCount int) C.ValuePtr {
 	ctx := getContext(ctxref)
 
-	this := *thisAndArgs
+	ptrs := (*[1 << 30]C.ValuePtr)(unsafe.Pointer(thisAndArgs))[: argsCount+2 : argsCount+2]
 	info := &FunctionCallbackInfo{
 		ctx:  ctx,
-		this: &Object{&Value{ptr: this, ctx: ctx}},
+		this: &Object{&Value{ptr: ptrs[0], ctx: ctx}},
 		args: make([]*Value, argsCount),
 	}
+	if ptrs[1] != nil {
+		info.newTarget = &Value{ptr: ptrs[1], ctx: ctx}
+	}
 
-	argv := (*[1 << 30]C.ValuePtr)(unsafe.Pointer(thisAndArgs))[1 : argsCount+1 : argsCount+1]
+	argv := ptrs[2:]
 	for i, v := range argv {
 		val := &Value{ptr: v, ctx: ctx}
 		info.args[i] = val
@@ -5,6 +5,7 @@
 package v8go_test
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
@@ -148,6 +149,113 @@ func TestFunctionCallbackInfoThis(t *testing.T) {
 	}
 }
 
+func TestFunctionTemplateClass(t *testing.T) {
+	t.Parallel()
+
+	iso := v8.NewIsolate()
+	defer iso.Dispose()
+
+	type animal struct{ name string }
+	var animals []*animal
+
+	animalTmpl := v8.NewFunctionTemplate(iso, func(info *v8.FunctionCallbackInfo) *v8.Value {
+		if !info.IsConstructCall() {
+			msg, _ := v8.NewValue(iso, "Animal must be called with new")
+			return iso.ThrowException(msg)
+		}
+		animals = append(animals, &animal{name: info.Args()[0].String()})
+		id, _ := v8.NewValue(iso, int32(len(animals)-1))
+		info.This().SetInternalField(0, id)
+		return nil
+	})
+	animalTmpl.SetClassName("Animal")
+	animalTmpl.InstanceTemplate().SetInternalFieldCount(1)
+	animalTmpl.PrototypeTemplate().Set("speak", v8.NewFunctionTemplate(iso, func(info *v8.FunctionCallbackInfo) *v8.Value {
+		a := animals[info.This().GetInternalField(0).Int32()]
+		val, _ := v8.NewValue(iso, a.name+" makes a sound")
+		return val
+	}))
+
+	var newTarget *v8.Value
+	dogTmpl := v8.NewFunctionTemplate(iso, func(info *v8.FunctionCallbackInfo) *v8.Value {
+		newTarget = info.NewTarget()
+		return nil
+	})
+	dogTmpl.SetClassName("Dog")
+	dogTmpl.Inherit(animalTmpl)
+
+	ctx := v8.NewContext(iso)
+	defer ctx.Close()
+	global := ctx.Global()
+	fatalIf(t, global.Set("Animal", animalTmpl.GetFunction(ctx)))
+	dog := dogTmpl.GetFunction(ctx)
+	fatalIf(t, global.Set("Dog", dog))
+
+	val, err := ctx.RunScript(`
+		const cat = new Animal("cat");
+		[cat.speak(), cat instanceof Animal, Object.prototype.toString.call(cat), new Dog() instanceof Animal].join(" ")
+	`, "")
+	fatalIf(t, err)
+	if s := val.String(); s != "cat makes a sound true [object Animal] true" {
+		t.Errorf("unexpected value: %q", s)
+	}
+	if !newTarget.SameValue(dog.Value) {
+		t.Errorf("expected new.target to be Dog, got %v", newTarget)
+	}
+
+	if _, err := ctx.RunScript(`Animal("cat")`, ""); err == nil {
+		t.Error("expected error calling constructor without new")
+	}
+}
+
+type valueError struct {
+	*v8.Value
+}
+
+func (e valueError) Error() string { return e.String() }
+
+func TestFunctionTemplateWithError(t *testing.T) {
+	t.Parallel()
+
+	iso := v8.NewIsolate()
+	defer iso.Dispose()
+	ctx := v8.NewContext(iso)
+	defer ctx.Close()
+
+	errNotFound := errors.New("not found")
+	fail := v8.NewFunctionTemplateWithError(iso, func(info *v8.FunctionCallbackInfo) (*v8.Value, error) {
+		switch arg := info.Args()[0].String(); arg {
+		case "wrapped":
+			return nil, fmt.Errorf("loading config: %w", errNotFound)
+		case "value":
+			obj, err := ctx.RunScript(`({code: 42})`, "")
+			fatalIf(t, err)
+			return nil, fmt.Errorf("custom: %w", valueError{obj})
+		case "ok":
+			return v8.NewValue(iso, "ok")
+		default:
+			return nil, errNotFound
+		}
+	})
+	fatalIf(t, ctx.Global().Set("fail", fail.GetFunction(ctx)))
+
+	tests := [...]struct {
+		script, expected string
+	}{
+		{`fail("ok")`, "ok"},
+		{`try { fail("plain") } catch (e) { [e instanceof Error, e.message, "cause" in e].join() }`, "true,not found,false"},
+		{`try { fail("wrapped") } catch (e) { [e.message, e.cause.message, Object.keys(e).length].join() }`, "loading config: not found,not found,0"},
+		{`try { fail("value") } catch (e) { e.code }`, "42"},
+	}
+	for _, tt := range tests {
+		val, err := ctx.RunScript(tt.script, "")
+		fatalIf(t, err)
+		if s := val.String(); s != tt.expected {
+			t.Errorf("unexpected result for %s: %q", tt.script, s)
+		}
+	}
+}
+
 func ExampleFunctionTemplate() {
 	iso := v8.NewIsolate()
 	defer iso.Dispose()
@@ -5,7 +5,10 @@
 package v8go_test
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 
 	v8 "rogchap.com/v8go"
 )
@@ -112,10 +115,36 @@ func TestFunctionCallError(t *testing.T) {
 	if err == nil {
 		t.Errorf("expected an error, got none")
 	}
-	got := *(err.(*v8.JSError))
-	want := v8.JSError{Message: "error", Location: "script.js:1:21"}
-	if got != want {
-		t.Errorf("want %+v, got: %+v", want, got)
+	got := err.(*v8.JSError)
+	if got.Message != "error" || got.Location != "script.js:1:21" || got.Name != "" {
+		t.Errorf("unexpected error: %+v", got)
+	}
+	if len(got.Frames) != 1 || got.Frames[0].FunctionName != "throws" {
+		t.Errorf("unexpected frames: %+v", got.Frames)
+	}
+}
+
+func TestFunctionCallContext(t *testing.T) {
+	t.Parallel()
+
+	ctx := v8.NewContext()
+	iso := ctx.Isolate()
+	defer iso.Dispose()
+	defer ctx.Close()
+
+	val, err := ctx.RunScript(`(function loop() { while (true) {} })`, "")
+	fatalIf(t, err)
+	fn, err := val.AsFunction()
+	fatalIf(t, err)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	_, err = fn.CallContext(cancelled, v8.Undefined(iso))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected error to wrap context.Canceled, got %v", err)
 	}
 }
 
@@ -196,9 +225,11 @@ func TestFunctionNewInstanceError(t *testing.T) {
 	if err == nil {
 		t.Errorf("expected an error, got none")
 	}
-	got := *(err.(*v8.JSError))
-	want := v8.JSError{Message: "error", Location: "script.js:1:21"}
-	if got != want {
-		t.Errorf("want %+v, got: %+v", want, got)
+	got := err.(*v8.JSError)
+	if got.Message != "error" || got.Location != "script.js:1:21" {
+		t.Errorf("unexpected error: %+v", got)
+	}
+	if len(got.Frames) != 1 || !got.Frames[0].IsConstructor {
+		t.Errorf("unexpected frames: %+v", got.Frames)
 	}
 }
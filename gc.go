@@ -0,0 +1,159 @@
+// Copyright 2023 the v8go contributors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package v8go
+
+// #include "v8go.h"
+import "C"
+import "time"
+
+// GCType is the type of a garbage collection.
+type GCType int
+
+const (
+	// GCTypeScavenge collects the young generation.
+	GCTypeScavenge GCType = 1 << iota
+	// GCTypeMinorMarkCompact collects the young generation by marking.
+	GCTypeMinorMarkCompact
+	// GCTypeMarkSweepCompact is a full garbage collection.
+	GCTypeMarkSweepCompact
+	// GCTypeIncrementalMarking is a step of the incremental marking of a
+	// full garbage collection.
+	GCTypeIncrementalMarking
+	// GCTypeProcessWeakCallbacks processes the callbacks of weak handles.
+	GCTypeProcessWeakCallbacks
+)
+
+func (t GCType) String() string {
+	switch t {
+	case GCTypeScavenge:
+		return "scavenge"
+	case GCTypeMinorMarkCompact:
+		return "minor-mark-compact"
+	case GCTypeMarkSweepCompact:
+		return "mark-sweep-compact"
+	case GCTypeIncrementalMarking:
+		return "incremental-marking"
+	case GCTypeProcessWeakCallbacks:
+		return "process-weak-callbacks"
+	}
+	return "unknown"
+}
+
+// GCCallbackFlags describe why a garbage collection happens.
+type GCCallbackFlags int
+
+const (
+	GCCallbackFlagConstructRetainedObjectInfos GCCallbackFlags = 1 << (iota + 1)
+	// GCCallbackFlagForced is set when the collection was forced, eg. for
+	// testing.
+	GCCallbackFlagForced
+	GCCallbackFlagSynchronousPhantomCallbackProcessing
+	// GCCallbackFlagCollectAllAvailableGarbage is set when the collection
+	// frees as much memory as possible, eg. on memory pressure.
+	GCCallbackFlagCollectAllAvailableGarbage
+	GCCallbackFlagCollectAllExternalMemory
+	GCCallbackScheduleIdleGarbageCollection
+)
+
+// GCEvent is the start (prologue) or end (epilogue) of a garbage collection.
+type GCEvent struct {
+	Type  GCType
+	Flags GCCallbackFlags
+	// Duration is the time the collection paused the Isolate, from its
+	// prologue to its epilogue; it is zero in the prologue.
+	Duration time.Duration
+}
+
+// GCCallback is called before or after a garbage collection of an Isolate.
+// The callback is called during garbage collection and must not call back
+// into the Isolate.
+type GCCallback func(GCEvent)
+
+// SetGCPrologueCallback sets the callback called when a garbage collection
+// starts; nil removes it.
+func (i *Isolate) SetGCPrologueCallback(cb GCCallback) {
+	i.gcMutex.Lock()
+	i.gcPrologueCb = cb
+	enabled := i.gcPrologueCb != nil || i.gcEpilogueCb != nil
+	i.gcMutex.Unlock()
+	i.setGCCallbacks(enabled)
+}
+
+// SetGCEpilogueCallback sets the callback called when a garbage collection
+// ends, with its duration; nil removes it.
+func (i *Isolate) SetGCEpilogueCallback(cb GCCallback) {
+	i.gcMutex.Lock()
+	i.gcEpilogueCb = cb
+	enabled := i.gcPrologueCb != nil || i.gcEpilogueCb != nil
+	i.gcMutex.Unlock()
+	i.setGCCallbacks(enabled)
+}
+
+func (i *Isolate) setGCCallbacks(enabled bool) {
+	var e C.int
+	if enabled {
+		e = 1
+	}
+	C.IsolateSetGCCallbacks(i.ptr, e)
+}
+
+// GCTypeStatistics aggregates the garbage collections of a type.
+type GCTypeStatistics struct {
+	Count      uint64
+	TotalPause time.Duration
+}
+
+// GCStatistics aggregates the garbage collections of an isolate by type,
+// since it was created.
+type GCStatistics struct {
+	Scavenge             GCTypeStatistics
+	MinorMarkCompact     GCTypeStatistics
+	MarkSweepCompact     GCTypeStatistics
+	IncrementalMarking   GCTypeStatistics
+	ProcessWeakCallbacks GCTypeStatistics
+}
+
+// GetGCStatistics returns the count and total pause of the garbage
+// collections of the isolate by type. Unlike GC callbacks it has no cost,
+// and it can be called from any goroutine, eg. to export the counters as
+// metrics along with GetHeapStatistics.
+func (i *Isolate) GetGCStatistics() GCStatistics {
+	gs := C.IsolateGetGCStatistics(i.ptr)
+	stat := func(idx int) GCTypeStatistics {
+		return GCTypeStatistics{
+			Count:      uint64(gs.count[idx]),
+			TotalPause: time.Duration(gs.total_pause_ns[idx]),
+		}
+	}
+	return GCStatistics{
+		Scavenge:             stat(0),
+		MinorMarkCompact:     stat(1),
+		MarkSweepCompact:     stat(2),
+		IncrementalMarking:   stat(3),
+		ProcessWeakCallbacks: stat(4),
+	}
+}
+
+//export goGCCallback
+func goGCCallback(ref int, epilogue C.int, gcType C.int, flags C.int, pause C.int64_t) {
+	iso := getIsolate(ref)
+	if iso == nil {
+		return
+	}
+	iso.gcMutex.RLock()
+	cb := iso.gcPrologueCb
+	if epilogue != 0 {
+		cb = iso.gcEpilogueCb
+	}
+	iso.gcMutex.RUnlock()
+	if cb == nil {
+		return
+	}
+	cb(GCEvent{
+		Type:     GCType(gcType),
+		Flags:    GCCallbackFlags(flags),
+		Duration: time.Duration(pause),
+	})
+}
@@ -0,0 +1,94 @@
+// Copyright 2023 the v8go contributors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package v8go_test
+
+import (
+	"sync"
+	"testing"
+
+	v8 "rogchap.com/v8go"
+)
+
+func TestIsolateGCCallbacks(t *testing.T) {
+	t.Parallel()
+
+	iso := v8.NewIsolate()
+	defer iso.Dispose()
+	ctx := v8.NewContext(iso)
+	defer ctx.Close()
+
+	var mu sync.Mutex
+	var prologues, epilogues []v8.GCEvent
+	iso.SetGCPrologueCallback(func(e v8.GCEvent) {
+		mu.Lock()
+		prologues = append(prologues, e)
+		mu.Unlock()
+	})
+	iso.SetGCEpilogueCallback(func(e v8.GCEvent) {
+		mu.Lock()
+		epilogues = append(epilogues, e)
+		mu.Unlock()
+	})
+
+	before := iso.GetGCStatistics()
+	// allocate enough short-lived objects to fill the young generation
+	_, err := ctx.RunScript(`for (let i = 0; i < 1000000; i++) { ({ value: "item" + i }) }`, "gc.js")
+	fatalIf(t, err)
+	after := iso.GetGCStatistics()
+
+	mu.Lock()
+	prologueEvents, epilogueEvents := prologues, epilogues
+	mu.Unlock()
+	scavenges := after.Scavenge.Count - before.Scavenge.Count
+	if scavenges == 0 {
+		t.Fatal("expected scavenges")
+	}
+	if after.Scavenge.TotalPause <= before.Scavenge.TotalPause {
+		t.Errorf("expected the scavenges to pause the isolate: %+v", after.Scavenge)
+	}
+	if len(prologueEvents) != len(epilogueEvents) {
+		t.Errorf("expected as many prologues as epilogues, got %d and %d", len(prologueEvents), len(epilogueEvents))
+	}
+	var count uint64
+	for _, e := range epilogueEvents {
+		if e.Type == v8.GCTypeScavenge {
+			count++
+		}
+		if e.Duration < 0 {
+			t.Errorf("unexpected duration of %v", e)
+		}
+	}
+	if count != scavenges {
+		t.Errorf("expected %d scavenge epilogues, got %d", scavenges, count)
+	}
+	for _, e := range prologueEvents {
+		if e.Duration != 0 {
+			t.Errorf("unexpected duration of prologue %v", e)
+		}
+	}
+
+	// removing the callbacks keeps the statistics
+	iso.SetGCPrologueCallback(nil)
+	iso.SetGCEpilogueCallback(nil)
+	_, err = ctx.RunScript(`for (let i = 0; i < 1000000; i++) { ({ value: "item" + i }) }`, "gc.js")
+	fatalIf(t, err)
+	mu.Lock()
+	n := len(epilogues)
+	mu.Unlock()
+	if n != len(epilogueEvents) {
+		t.Error("expected no callbacks once removed")
+	}
+	if iso.GetGCStatistics().Scavenge.Count <= after.Scavenge.Count {
+		t.Error("expected the scavenges to be counted")
+	}
+}
+
+func TestGCTypeString(t *testing.T) {
+	t.Parallel()
+
+	if s := v8.GCTypeMarkSweepCompact.String(); s != "mark-sweep-compact" {
+		t.Errorf("unexpected name %q", s)
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright 2023 the v8go contributors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package v8go
+
+/*
+#include <stdlib.h>
+#include "v8go.h"
+*/
+import "C"
+import (
+	"errors"
+	"io"
+	"sync"
+	"unsafe"
+)
+
+// DefaultHeapSampleInterval is the default average number of bytes allocated
+// between two sampled allocations.
+const DefaultHeapSampleInterval = 512 * 1024
+
+// DefaultHeapSampleStackDepth is the default maximum number of stack frames
+// recorded for a sampled allocation.
+const DefaultHeapSampleStackDepth = 16
+
+// HeapProfiler profiles the JavaScript heap of an Isolate.
+type HeapProfiler struct {
+	iso *Isolate
+
+	// The sample interval of the running sampling, for the period of the
+	// pprof profiles.
+	sampleInterval uint64
+}
+
+// NewHeapProfiler returns the HeapProfiler of the Isolate. Unlike a
+// CPUProfiler, it is owned by the Isolate and doesn't need to be disposed.
+func NewHeapProfiler(iso *Isolate) *HeapProfiler {
+	return &HeapProfiler{iso: iso}
+}
+
+// HeapSamplingOptions configures HeapProfiler.StartSampling.
+type HeapSamplingOptions struct {
+	// SampleInterval is the average number of bytes allocated between two
+	// sampled allocations. Zero means DefaultHeapSampleInterval.
+	SampleInterval uint64
+
+	// StackDepth is the maximum number of stack frames recorded for each
+	// sampled allocation. Zero means DefaultHeapSampleStackDepth.
+	StackDepth int
+
+	// IncludeObjectsCollectedByMajorGC and IncludeObjectsCollectedByMinorGC
+	// keep the sampled objects that were garbage collected in the profile, to
+	// profile all the allocations rather than the live objects only.
+	IncludeObjectsCollectedByMajorGC bool
+	IncludeObjectsCollectedByMinorGC bool
+}
+
+// Values of v8::HeapProfiler::SamplingFlags.
+const (
+	heapSamplingIncludeObjectsCollectedByMajorGC = 1 << 1
+	heapSamplingIncludeObjectsCollectedByMinorGC = 1 << 2
+)
+
+// ErrHeapSamplingStarted is returned by HeapProfiler.StartSampling when
+// sampling is already running.
+var ErrHeapSamplingStarted = errors.New("v8go: heap sampling is already started")
+
+// StartSampling starts sampling the allocations of the Isolate: on average,
+// one allocation is sampled every SampleInterval bytes, with the stack of the
+// JavaScript functions that made it. It is cheap enough to run in production.
+// Objects allocated before sampling started are not included.
+func (h *HeapProfiler) StartSampling(opts HeapSamplingOptions) error {
+	if h.iso.ptr == nil {
+		panic("isolate is nil")
+	}
+	if opts.SampleInterval == 0 {
+		opts.SampleInterval = DefaultHeapSampleInterval
+	}
+	if opts.StackDepth == 0 {
+		opts.StackDepth = DefaultHeapSampleStackDepth
+	}
+	var flags int
+	if opts.IncludeObjectsCollectedByMajorGC {
+		flags |= heapSamplingIncludeObjectsCollectedByMajorGC
+	}
+	if opts.IncludeObjectsCollectedByMinorGC {
+		flags |= heapSamplingIncludeObjectsCollectedByMinorGC
+	}
+	if C.HeapProfilerStartSampling(h.iso.ptr, C.uint64_t(opts.SampleInterval), C.int(opts.StackDepth), C.int(flags)) == 0 {
+		return ErrHeapSamplingStarted
+	}
+	h.sampleInterval = opts.SampleInterval
+	return nil
+}
+
+// StopSampling stops sampling and discards the sampled allocations, so the
+// profile must be retrieved with GetAllocationProfile before.
+func (h *HeapProfiler) StopSampling() {
+	if h.iso.ptr == nil {
+		panic("isolate is nil")
+	}
+	C.HeapProfilerStopSampling(h.iso.ptr)
+}
+
+// GetAllocationProfile returns the call tree of the sampled allocations that
+// are still live, or nil if sampling is not running. Sampling continues, so
+// it can be called repeatedly to watch the heap grow.
+func (h *HeapProfiler) GetAllocationProfile() *AllocationProfile {
+	if h.iso.ptr == nil {
+		panic("isolate is nil")
+	}
+	root := C.HeapProfilerGetAllocationProfile(h.iso.ptr)
+	if root == nil {
+		return nil
+	}
+	defer C.AllocationProfileNodeDelete(root)
+	return &AllocationProfile{
+		root:           newAllocationProfileNode(root, nil),
+		sampleInterval: h.sampleInterval,
+	}
+}
+
+// HeapSnapshotOptions configures HeapProfiler.TakeHeapSnapshot.
+type HeapSnapshotOptions struct {
+	// ExposeNumericValues adds the values of numbers to the snapshot, as
+	// artificial fields of the objects that hold them.
+	ExposeNumericValues bool
+
+	// ExposeInternals adds the V8 internals that are only useful to experts.
+	ExposeInternals bool
+
+	// ContextGlobalName, if not nil, names the global object of each Context
+	// in the snapshot, eg. after the Go component that created the Context,
+	// to tell apart the objects it retains. It must not call back into the
+	// Isolate. An empty name keeps the name given by V8.
+	ContextGlobalName func(ctx *Context) string
+
+	// ObjectNames names objects in the snapshot, eg. the instances of Go
+	// templates after the Go values they are backed by, so that they can be
+	// found among the plain objects. The objects are shown as native objects
+	// under their names. They must belong to the Isolate of the HeapProfiler.
+	ObjectNames map[*Object]string
+}
+
+// As with isolates, the writers of the snapshots being taken are kept in a
+// registry so that they can be looked up by reference from V8.
+var snapshotMutex sync.Mutex
+var snapshotRegistry = make(map[int]*heapSnapshotWriter)
+var snapshotSeq = 0
+
+type heapSnapshotWriter struct {
+	w    io.Writer
+	err  error
+	name func(*Context) string
+}
+
+// TakeHeapSnapshot takes a snapshot of the heap of the Isolate and writes it
+// to w in the JSON format of the .heapsnapshot files that are loaded in the
+// Memory panel of Chrome DevTools, to find the objects that are leaking and
+// what retains them. The snapshot is streamed in chunks while it is
+// serialized. Taking a snapshot collects garbage and blocks the Isolate.
+func (h *HeapProfiler) TakeHeapSnapshot(w io.Writer, opts HeapSnapshotOptions) error {
+	if h.iso.ptr == nil {
+		panic("isolate is nil")
+	}
+
+	sw := &heapSnapshotWriter{w: w, name: opts.ContextGlobalName}
+	snapshotMutex.Lock()
+	snapshotSeq++
+	ref := snapshotSeq
+	snapshotRegistry[ref] = sw
+	snapshotMutex.Unlock()
+	defer func() {
+		snapshotMutex.Lock()
+		delete(snapshotRegistry, ref)
+		snapshotMutex.Unlock()
+	}()
+
+	var copts C.HeapSnapshotOptions
+	if opts.ExposeNumericValues {
+		copts.exposeNumericValues = 1
+	}
+	if opts.ExposeInternals {
+		copts.exposeInternals = 1
+	}
+	if opts.ContextGlobalName != nil {
+		copts.nameGlobalObjects = 1
+	}
+	if n := len(opts.ObjectNames); n > 0 {
+		ptr := (*C.HeapSnapshotObjectName)(C.malloc(C.size_t(n) * C.size_t(unsafe.Sizeof(C.HeapSnapshotObjectName{}))))
+		defer C.free(unsafe.Pointer(ptr))
+		names := unsafe.Slice(ptr, n)
+		i := 0
+		for obj, name := range opts.ObjectNames {
+			if obj.ctx.iso != h.iso {
+				panic("v8go: object does not belong to the Isolate of the HeapProfiler")
+			}
+			names[i].object = obj.ptr
+			names[i].name = C.CString(name)
+			defer C.free(unsafe.Pointer(names[i].name))
+			i++
+		}
+		copts.objectNames = ptr
+		copts.objectNamesCount = C.int(n)
+	}
+	C.HeapProfilerTakeHeapSnapshot(h.iso.ptr, C.int(ref), copts)
+	return sw.err
+}
+
+func getHeapSnapshotWriter(ref int) *heapSnapshotWriter {
+	snapshotMutex.Lock()
+	defer snapshotMutex.Unlock()
+	return snapshotRegistry[ref]
+}
+
+//export goHeapSnapshotWrite
+func goHeapSnapshotWrite(ref int, data *C.char, size C.int) C.int {
+	sw := getHeapSnapshotWriter(ref)
+	if sw == nil {
+		return 0
+	}
+	if _, err := sw.w.Write(C.GoBytes(unsafe.Pointer(data), size)); err != nil {
+		sw.err = err
+		return 0
+	}
+	return 1
+}
+
+//export goHeapSnapshotGlobalObjectName
+func goHeapSnapshotGlobalObjectName(ref int, ctxref int) *C.char {
+	sw := getHeapSnapshotWriter(ref)
+	ctx := getContext(ctxref)
+	if sw == nil || sw.name == nil || ctx == nil {
+		return nil
+	}
+	name := sw.name(ctx)
+	if name == "" {
+		return nil
+	}
+	return C.CString(name)
+}
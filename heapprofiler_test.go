@@ -0,0 +1,167 @@
+// Copyright 2023 the v8go contributors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package v8go_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	v8 "rogchap.com/v8go"
+)
+
+const allocScript = `var retained = [];
+function allocate() {
+  for (let i = 0; i < 100000; i++) {
+    retained.push({ index: i, name: "item" + i });
+  }
+}
+allocate();`
+
+func TestHeapProfiler(t *testing.T) {
+	t.Parallel()
+
+	ctx := v8.NewContext(nil)
+	iso := ctx.Isolate()
+	defer iso.Dispose()
+	defer ctx.Close()
+
+	heapProfiler := v8.NewHeapProfiler(iso)
+	if heapProfiler.GetAllocationProfile() != nil {
+		t.Error("expected no profile before sampling")
+	}
+
+	fatalIf(t, heapProfiler.StartSampling(v8.HeapSamplingOptions{SampleInterval: 1024}))
+	if err := heapProfiler.StartSampling(v8.HeapSamplingOptions{}); err != v8.ErrHeapSamplingStarted {
+		t.Errorf("expected ErrHeapSamplingStarted, got %v", err)
+	}
+
+	_, err := ctx.RunScript(allocScript, "alloc.js")
+	fatalIf(t, err)
+	profile := heapProfiler.GetAllocationProfile()
+	heapProfiler.StopSampling()
+	if profile == nil {
+		t.Fatal("expected a profile")
+	}
+
+	var found bool
+	var walk func(*v8.AllocationProfileNode)
+	walk = func(n *v8.AllocationProfileNode) {
+		if n.GetFunctionName() == "allocate" && n.GetScriptResourceName() == "alloc.js" {
+			for _, a := range n.GetAllocations() {
+				if a.Size <= 0 || a.Count <= 0 {
+					t.Errorf("unexpected allocation %+v", a)
+				}
+			}
+			found = found || len(n.GetAllocations()) > 0
+		}
+		for i := 0; i < n.GetChildrenCount(); i++ {
+			if n.GetChild(i).GetParent() != n {
+				t.Error("unexpected parent")
+			}
+			walk(n.GetChild(i))
+		}
+	}
+	walk(profile.GetRootNode())
+	if !found {
+		t.Error("expected allocations by allocate")
+	}
+
+	var buf bytes.Buffer
+	fatalIf(t, profile.WritePprof(&buf))
+	pprof := decodePprof(t, &buf)
+	if len(pprof.samples) == 0 || len(pprof.locations) == 0 || pprof.functions == 0 {
+		t.Errorf("expected samples, locations and functions, got %d, %d and %d", len(pprof.samples), len(pprof.locations), pprof.functions)
+	}
+	for _, locs := range pprof.samples {
+		for _, id := range locs {
+			if !pprof.locations[id] {
+				t.Errorf("sample with unknown location %d", id)
+			}
+		}
+	}
+	// the string table holds the names of the sample types and functions
+	for _, s := range []string{"inuse_space", "bytes", "allocate", "alloc.js"} {
+		if !pprof.hasString(s) {
+			t.Errorf("expected %q in the profile", s)
+		}
+	}
+
+	if heapProfiler.GetAllocationProfile() != nil {
+		t.Error("expected no profile after sampling stopped")
+	}
+}
+
+type errWriter struct{ n int }
+
+func (w *errWriter) Write(p []byte) (int, error) {
+	w.n++
+	return 0, errors.New("write failed")
+}
+
+func TestHeapProfiler_TakeHeapSnapshot(t *testing.T) {
+	t.Parallel()
+
+	ctx := v8.NewContext(nil)
+	iso := ctx.Isolate()
+	defer iso.Dispose()
+	defer ctx.Close()
+
+	_, err := ctx.RunScript("class Leaky {}; var leaks = [new Leaky(), new Leaky()]; var ratio = 0.25;", "leak.js")
+	fatalIf(t, err)
+
+	tmpl := v8.NewObjectTemplate(iso)
+	tmpl.SetInternalFieldCount(1)
+	conn, err := tmpl.NewInstance(ctx)
+	fatalIf(t, err)
+	fatalIf(t, ctx.Global().Set("conn", conn))
+
+	heapProfiler := v8.NewHeapProfiler(iso)
+	var buf bytes.Buffer
+	fatalIf(t, heapProfiler.TakeHeapSnapshot(&buf, v8.HeapSnapshotOptions{
+		ExposeNumericValues: true,
+		ContextGlobalName: func(c *v8.Context) string {
+			if c == ctx {
+				return "tenant-42"
+			}
+			return ""
+		},
+		ObjectNames: map[*v8.Object]string{conn: "GoConn"},
+	}))
+
+	var snapshot struct {
+		Snapshot struct {
+			NodeCount int `json:"node_count"`
+		} `json:"snapshot"`
+		Strings []string `json:"strings"`
+	}
+	fatalIf(t, json.Unmarshal(buf.Bytes(), &snapshot))
+	if snapshot.Snapshot.NodeCount == 0 {
+		t.Error("expected nodes in the snapshot")
+	}
+	strs := make(map[string]bool)
+	for _, s := range snapshot.Strings {
+		strs[s] = true
+	}
+	for _, s := range []string{"Leaky", "0.25", "GoConn"} {
+		if !strs[s] {
+			t.Errorf("expected %q in the snapshot", s)
+		}
+	}
+	var named bool
+	for _, s := range snapshot.Strings {
+		named = named || bytes.Contains([]byte(s), []byte("tenant-42"))
+	}
+	if !named {
+		t.Error("expected the global object to be named")
+	}
+
+	// a failing writer aborts the serialization
+	w := &errWriter{}
+	if err := heapProfiler.TakeHeapSnapshot(w, v8.HeapSnapshotOptions{}); err == nil || w.n != 1 {
+		t.Errorf("expected the write error once, got %v after %d writes", err, w.n)
+	}
+}
@@ -1,6 +1,11 @@
 package v8go_test
 
-import "testing"
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"testing"
+)
 
 func fatalIf(t *testing.T, err error) {
 	t.Helper()
@@ -16,3 +21,97 @@ func recoverPanic(f func()) (recovered interface{}) {
 	f()
 	return nil
 }
+
+// pprofProfile holds the parts of a decoded pprof profile that tests check.
+type pprofProfile struct {
+	samples   [][]uint64 // the location ids of each sample
+	locations map[uint64]bool
+	functions int
+	strings   []string
+}
+
+func (p *pprofProfile) hasString(s string) bool {
+	for _, str := range p.strings {
+		if str == s {
+			return true
+		}
+	}
+	return false
+}
+
+// decodePprof decodes the gzipped protocol buffer of a pprof profile.
+func decodePprof(t *testing.T, r io.Reader) *pprofProfile {
+	t.Helper()
+	zr, err := gzip.NewReader(r)
+	fatalIf(t, err)
+	data, err := io.ReadAll(zr)
+	fatalIf(t, err)
+
+	p := &pprofProfile{locations: make(map[uint64]bool)}
+	forEachField(t, data, func(field int, v uint64, b []byte) {
+		switch field {
+		case 2: // sample
+			var locs []uint64
+			forEachField(t, b, func(field int, v uint64, b []byte) {
+				if field != 1 {
+					return
+				}
+				if b == nil {
+					locs = append(locs, v)
+					return
+				}
+				for len(b) > 0 {
+					id, n := binary.Uvarint(b)
+					if n <= 0 {
+						t.Fatal("invalid packed location ids")
+					}
+					locs = append(locs, id)
+					b = b[n:]
+				}
+			})
+			p.samples = append(p.samples, locs)
+		case 4: // location
+			forEachField(t, b, func(field int, v uint64, b []byte) {
+				if field == 1 {
+					p.locations[v] = true
+				}
+			})
+		case 5: // function
+			p.functions++
+		case 6: // string_table
+			p.strings = append(p.strings, string(b))
+		}
+	})
+	return p
+}
+
+// forEachField calls fn with each field of a protocol buffer message, with
+// either the value of a varint or the bytes of a length-delimited field.
+func forEachField(t *testing.T, data []byte, fn func(field int, v uint64, b []byte)) {
+	t.Helper()
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			t.Fatal("invalid field key")
+		}
+		data = data[n:]
+		switch key & 7 {
+		case 0:
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				t.Fatal("invalid varint")
+			}
+			data = data[n:]
+			fn(int(key>>3), v, nil)
+		case 2:
+			l, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < l {
+				t.Fatal("invalid length-delimited field")
+			}
+			fn(int(key>>3), 0, data[n:n+int(l)])
+			data = data[n+int(l):]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+	}
+}
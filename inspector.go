@@ -0,0 +1,381 @@
+// Copyright 2023 the v8go contributors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package v8go
+
+// #include <stdlib.h>
+// #include "v8go.h"
+import "C"
+import (
+	"sync"
+	"unicode/utf16"
+	"unsafe"
+)
+
+// Inspector debugs the scripts of an Isolate with the Chrome DevTools
+// Protocol (CDP), backed by V8's inspector. Each InspectorSession exchanges
+// CDP messages with a debugger such as Chrome DevTools; the inspector
+// subpackage connects sessions to channels, streams and WebSockets.
+//
+// The messages of the sessions are dispatched on the thread of the Isolate:
+// while a script runs they interrupt it, and while a session pauses
+// execution, eg. at a breakpoint, the thread of the Isolate is blocked
+// until the debugger resumes it.
+type Inspector struct {
+	ptr C.InspectorPtr
+	iso *Isolate
+
+	mu sync.Mutex
+	// pending are the operations to run on the thread of the Isolate.
+	pending []func()
+	// wake is signaled when an operation is pending.
+	wake chan struct{}
+	// paused is set while a session pauses execution, and quitPause when
+	// it resumes.
+	paused    bool
+	quitPause bool
+	// idle is set while a goroutine waits to run the pending operations
+	// without interrupting a script.
+	idle     bool
+	idleDone sync.WaitGroup
+	// interrupt is set while a script is requested to run the pending
+	// operations; the request is only served once a script runs.
+	interrupt bool
+	debugger  chan struct{}
+	// disposed is closed by Dispose, which drops the pending operations.
+	disposed chan struct{}
+
+	sessionSeq int
+	sessions   map[int]*InspectorSession
+}
+
+// InspectorSession is a connection of a debugger to an Inspector.
+type InspectorSession struct {
+	ptr  C.InspectorSessionPtr
+	ins  *Inspector
+	ref  int
+	send func(message []byte)
+}
+
+// Inspector returns the Inspector of the Isolate, creating it on first use.
+// The contexts to debug must be registered with Inspector.ContextCreated.
+// Once created, console messages are reported to the sessions of the
+// Inspector rather than to the ConsoleDelegate of the Isolate, until
+// Isolate.SetConsoleDelegate is called again; a ConsoleDelegate set then is
+// kept when the Inspector is disposed.
+func (i *Isolate) Inspector() *Inspector {
+	// cbMutex is not held while the inspector is created, as V8 can call
+	// back into Go when it allocates, eg. with the near heap limit callback.
+	i.insMutex.Lock()
+	defer i.insMutex.Unlock()
+	if ins := i.getInspector(); ins != nil {
+		return ins
+	}
+	ins := &Inspector{
+		ptr:      C.NewInspector(i.ptr),
+		iso:      i,
+		wake:     make(chan struct{}, 1),
+		debugger: make(chan struct{}, 1),
+		disposed: make(chan struct{}),
+		sessions: make(map[int]*InspectorSession),
+	}
+	i.cbMutex.Lock()
+	i.inspector = ins
+	i.consoleDelegate = nil
+	i.cbMutex.Unlock()
+	return ins
+}
+
+func (i *Isolate) getInspector() *Inspector {
+	i.cbMutex.RLock()
+	defer i.cbMutex.RUnlock()
+	return i.inspector
+}
+
+// ContextCreated makes the Context visible to the sessions of the Inspector
+// under the given name, which debuggers show in their list of contexts.
+// The first context is the one used by evaluations that don't specify one.
+// A Context is removed when it is closed, or with ContextDestroyed.
+func (ins *Inspector) ContextCreated(ctx *Context, name string) {
+	if ctx.iso != ins.iso {
+		panic("v8go: context does not belong to the Isolate of the Inspector")
+	}
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	C.InspectorContextCreated(ins.ptr, ctx.ptr, cname)
+}
+
+// ContextDestroyed removes the Context from the sessions of the Inspector.
+func (ins *Inspector) ContextDestroyed(ctx *Context) {
+	C.InspectorContextDestroyed(ins.ptr, ctx.ptr)
+}
+
+// Connect creates a session of the Inspector, which calls send with each
+// CDP response and notification, encoded as JSON. send is called on the
+// thread of the Isolate and must not block or use the Isolate.
+// The session of a disposed Inspector is closed and ignores its messages.
+func (ins *Inspector) Connect(send func(message []byte)) *InspectorSession {
+	s := &InspectorSession{ins: ins, send: send}
+	done := make(chan struct{})
+	posted := ins.post(func() {
+		ins.mu.Lock()
+		ins.sessionSeq++
+		s.ref = ins.sessionSeq
+		ins.sessions[s.ref] = s
+		ins.mu.Unlock()
+		s.ptr = C.InspectorConnect(ins.ptr, C.int(s.ref))
+		close(done)
+	})
+	if posted {
+		select {
+		case <-done:
+		case <-ins.disposed:
+		}
+	}
+	return s
+}
+
+// WaitForDebugger blocks until a session sends Runtime.runIfWaitingForDebugger,
+// as debuggers do once they have attached and set their breakpoints, so that
+// these apply from the start of the scripts that run next.
+func (ins *Inspector) WaitForDebugger() {
+	<-ins.debugger
+}
+
+// Dispose disconnects the sessions of the Inspector and frees its resources.
+// It must not be called while a script runs in the Isolate, and is called
+// by Isolate.Dispose.
+func (ins *Inspector) Dispose() {
+	ins.mu.Lock()
+	if ins.ptr == nil {
+		ins.mu.Unlock()
+		return
+	}
+	ptr := ins.ptr
+	ins.ptr = nil
+	ins.pending = nil
+	sessions := ins.sessions
+	ins.sessions = nil
+	close(ins.disposed)
+	ins.mu.Unlock()
+
+	ins.idleDone.Wait()
+	for _, s := range sessions {
+		C.InspectorSessionDelete(s.ptr)
+		s.ptr = nil
+	}
+	C.InspectorDelete(ptr)
+
+	ins.iso.cbMutex.Lock()
+	ins.iso.inspector = nil
+	ins.iso.cbMutex.Unlock()
+}
+
+// DispatchMessage dispatches a CDP request, encoded as JSON, to the session.
+// It can be called from any goroutine, and returns before the request is
+// dispatched on the thread of the Isolate.
+func (s *InspectorSession) DispatchMessage(message []byte) {
+	s.dispatch(message)
+}
+
+// dispatch dispatches a CDP request as DispatchMessage, returning false if
+// the Inspector has been disposed.
+func (s *InspectorSession) dispatch(message []byte) bool {
+	msg := utf16.Encode([]rune(string(message)))
+	return s.ins.post(func() {
+		if s.ptr == nil || len(msg) == 0 {
+			return
+		}
+		C.InspectorSessionDispatchMessage(s.ptr, (*C.uint16_t)(unsafe.Pointer(&msg[0])), C.int(len(msg)))
+	})
+}
+
+// Disconnect closes the session, resuming execution if it is paused.
+func (s *InspectorSession) Disconnect() {
+	s.ins.post(func() {
+		if s.ptr == nil {
+			return
+		}
+		s.ins.mu.Lock()
+		delete(s.ins.sessions, s.ref)
+		s.ins.mu.Unlock()
+		C.InspectorSessionDelete(s.ptr)
+		s.ptr = nil
+	})
+}
+
+// post schedules fn to run on the thread of the Isolate: by the message loop
+// while execution is paused, by interrupting a running script, or else by a
+// goroutine that waits for the Isolate to be unused. It returns false if the
+// Inspector has been disposed; fn is not run either if the Inspector is
+// disposed before it runs, when ins.disposed is closed.
+func (ins *Inspector) post(fn func()) bool {
+	ins.mu.Lock()
+	if ins.ptr == nil {
+		ins.mu.Unlock()
+		return false
+	}
+	ins.pending = append(ins.pending, fn)
+	startIdle := ins.startIdle()
+	interrupt := !ins.interrupt
+	ins.interrupt = true
+	ptr := ins.ptr
+	ins.mu.Unlock()
+
+	select {
+	case ins.wake <- struct{}{}:
+	default:
+	}
+	if interrupt {
+		C.InspectorRequestInterrupt(ptr)
+	}
+	if startIdle {
+		go ins.runIdle(ptr)
+	}
+	return true
+}
+
+// startIdle returns whether a goroutine needs to be started to run the
+// pending operations; ins.mu must be held.
+func (ins *Inspector) startIdle() bool {
+	if ins.paused || ins.idle || len(ins.pending) == 0 {
+		return false
+	}
+	ins.idle = true
+	ins.idleDone.Add(1)
+	return true
+}
+
+func (ins *Inspector) runIdle(ptr C.InspectorPtr) {
+	defer ins.idleDone.Done()
+	C.InspectorRunPending(ptr)
+}
+
+// runPending runs the pending operations on the thread of the Isolate,
+// until there are none left.
+func (ins *Inspector) runPending() {
+	for {
+		ins.mu.Lock()
+		fns := ins.pending
+		ins.pending = nil
+		ins.mu.Unlock()
+		if len(fns) == 0 {
+			return
+		}
+		for _, fn := range fns {
+			fn()
+		}
+	}
+}
+
+func (ins *Inspector) runMessageLoopOnPause() {
+	ins.mu.Lock()
+	ins.paused = true
+	ins.quitPause = false
+	ins.mu.Unlock()
+
+	for {
+		ins.runPending()
+		ins.mu.Lock()
+		if ins.quitPause || ins.ptr == nil {
+			ins.paused = false
+			startIdle := ins.startIdle()
+			ptr := ins.ptr
+			ins.mu.Unlock()
+			if startIdle {
+				go ins.runIdle(ptr)
+			}
+			return
+		}
+		ins.mu.Unlock()
+		<-ins.wake
+	}
+}
+
+//export goInspectorRunPending
+func goInspectorRunPending(isoref int, idle C.int) {
+	iso := getIsolate(isoref)
+	if iso == nil {
+		return
+	}
+	ins := iso.getInspector()
+	if ins == nil {
+		return
+	}
+	if idle == 0 {
+		ins.mu.Lock()
+		ins.interrupt = false
+		ins.mu.Unlock()
+		ins.runPending()
+		return
+	}
+	ins.runPending()
+	ins.mu.Lock()
+	// Operations posted from now on start another goroutine if needed.
+	ins.idle = false
+	startIdle := ins.startIdle()
+	ptr := ins.ptr
+	ins.mu.Unlock()
+	if startIdle {
+		go ins.runIdle(ptr)
+	}
+}
+
+//export goInspectorRunMessageLoopOnPause
+func goInspectorRunMessageLoopOnPause(isoref int) {
+	if iso := getIsolate(isoref); iso != nil {
+		if ins := iso.getInspector(); ins != nil {
+			ins.runMessageLoopOnPause()
+		}
+	}
+}
+
+//export goInspectorQuitMessageLoopOnPause
+func goInspectorQuitMessageLoopOnPause(isoref int) {
+	if iso := getIsolate(isoref); iso != nil {
+		if ins := iso.getInspector(); ins != nil {
+			ins.mu.Lock()
+			ins.quitPause = true
+			ins.mu.Unlock()
+		}
+	}
+}
+
+//export goInspectorRunIfWaitingForDebugger
+func goInspectorRunIfWaitingForDebugger(isoref int) {
+	if iso := getIsolate(isoref); iso != nil {
+		if ins := iso.getInspector(); ins != nil {
+			select {
+			case ins.debugger <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+//export goInspectorSessionSend
+func goInspectorSessionSend(isoref int, sessionref int, data8 *C.char, data16 *C.uint16_t, length C.size_t) {
+	iso := getIsolate(isoref)
+	if iso == nil {
+		return
+	}
+	ins := iso.getInspector()
+	if ins == nil {
+		return
+	}
+	ins.mu.Lock()
+	s := ins.sessions[sessionref]
+	ins.mu.Unlock()
+	if s == nil {
+		return
+	}
+
+	var msg []byte
+	if data16 != nil {
+		msg = []byte(string(utf16.Decode(unsafe.Slice((*uint16)(unsafe.Pointer(data16)), int(length)))))
+	} else {
+		msg = C.GoBytes(unsafe.Pointer(data8), C.int(length))
+	}
+	s.send(msg)
+}
@@ -0,0 +1,174 @@
+// Copyright 2023 the v8go contributors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+// Package inspector connects debuggers such as Chrome DevTools or VS Code to
+// the scripts run by v8go, with the Chrome DevTools Protocol (CDP).
+//
+// A Context is made debuggable with Attach; sessions then exchange CDP
+// messages encoded as JSON through a channel (Target.Connect), a stream
+// (Target.ServeConn) or a WebSocket (Server). While a session pauses
+// execution, eg. at a breakpoint or `debugger` statement, the goroutine
+// running the script is blocked until the debugger resumes it.
+package inspector
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"sync"
+
+	v8 "rogchap.com/v8go"
+)
+
+// maxMessageSize is the size limit of the CDP messages read from streams
+// and WebSockets.
+const maxMessageSize = 64 << 20
+
+// Target is a Context that debuggers can connect to. The sessions of a
+// Target see all the attached contexts of its Isolate.
+type Target struct {
+	// ID identifies the Target in the URLs of a Server.
+	ID    string
+	Title string
+
+	ctx *v8.Context
+	ins *v8.Inspector
+}
+
+// Attach makes the Context debuggable under the given title, using the
+// Inspector of its Isolate. The Context is detached when it is closed.
+func Attach(ctx *v8.Context, title string) *Target {
+	ins := ctx.Isolate().Inspector()
+	ins.ContextCreated(ctx, title)
+	return &Target{
+		ID:    newTargetID(),
+		Title: title,
+		ctx:   ctx,
+		ins:   ins,
+	}
+}
+
+func newTargetID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// Context returns the Context of the Target.
+func (t *Target) Context() *v8.Context {
+	return t.ctx
+}
+
+// WaitForDebugger blocks until a debugger is attached and ready, so that its
+// breakpoints apply from the start of the scripts that run next.
+func (t *Target) WaitForDebugger() {
+	t.ins.WaitForDebugger()
+}
+
+// Session is a CDP session with a Target.
+type Session struct {
+	session  *v8.InspectorSession
+	messages chan []byte
+
+	mu     sync.Mutex
+	queue  [][]byte
+	notify chan struct{}
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// Connect creates a session with the Target. The session must be closed
+// once it is no longer used.
+func (t *Target) Connect() *Session {
+	s := &Session{
+		messages: make(chan []byte),
+		notify:   make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	s.session = t.ins.Connect(s.enqueue)
+	go s.deliver()
+	return s
+}
+
+// Send dispatches a CDP request, encoded as JSON, to the Target.
+func (s *Session) Send(message []byte) {
+	s.session.DispatchMessage(message)
+}
+
+// Messages returns the channel of the CDP responses and notifications of
+// the session. It is closed when the session is closed.
+func (s *Session) Messages() <-chan []byte {
+	return s.messages
+}
+
+// Close disconnects the session, resuming execution if it is paused.
+func (s *Session) Close() {
+	s.closeOnce.Do(func() {
+		s.session.Disconnect()
+		close(s.done)
+	})
+}
+
+// enqueue is called on the thread of the Isolate, which must not be blocked
+// by a slow reader of the messages.
+func (s *Session) enqueue(message []byte) {
+	s.mu.Lock()
+	s.queue = append(s.queue, message)
+	s.mu.Unlock()
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Session) deliver() {
+	defer close(s.messages)
+	for {
+		s.mu.Lock()
+		queue := s.queue
+		s.queue = nil
+		s.mu.Unlock()
+		for _, msg := range queue {
+			select {
+			case s.messages <- msg:
+			case <-s.done:
+				return
+			}
+		}
+		select {
+		case <-s.notify:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// ServeConn runs a session with the Target over a stream of CDP messages,
+// each on its own line, until the stream is closed by the other side.
+func (t *Target) ServeConn(rw io.ReadWriter) error {
+	s := t.Connect()
+	defer s.Close()
+
+	go func() {
+		for msg := range s.Messages() {
+			if _, err := rw.Write(append(msg, '\n')); err != nil {
+				return
+			}
+		}
+	}()
+
+	sc := bufio.NewScanner(rw)
+	sc.Buffer(make([]byte, 64<<10), maxMessageSize)
+	for sc.Scan() {
+		if line := bytes.TrimSpace(sc.Bytes()); len(line) > 0 {
+			s.Send(line)
+		}
+	}
+	return sc.Err()
+}
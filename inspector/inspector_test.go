@@ -0,0 +1,252 @@
+// Copyright 2023 the v8go contributors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package inspector_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	v8 "rogchap.com/v8go"
+	"rogchap.com/v8go/inspector"
+)
+
+type message struct {
+	ID     int             `json:"id"`
+	Method string          `json:"method"`
+	Result json.RawMessage `json:"result"`
+	Params json.RawMessage `json:"params"`
+}
+
+// waitFor returns the first message of the session that matches.
+func waitFor(t *testing.T, msgs <-chan []byte, match func(message) bool) message {
+	t.Helper()
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case raw, ok := <-msgs:
+			if !ok {
+				t.Fatal("session closed")
+			}
+			var msg message
+			if err := json.Unmarshal(raw, &msg); err != nil {
+				t.Fatalf("invalid message %s: %v", raw, err)
+			}
+			if match(msg) {
+				return msg
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for message")
+		}
+	}
+}
+
+func TestSessionEvaluate(t *testing.T) {
+	t.Parallel()
+
+	iso := v8.NewIsolate()
+	defer iso.Dispose()
+	ctx := v8.NewContext(iso)
+	defer ctx.Close()
+	if _, err := ctx.RunScript("const answer = 42", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	target := inspector.Attach(ctx, "test")
+	session := target.Connect()
+	defer session.Close()
+
+	session.Send([]byte(`{"id":1,"method":"Runtime.evaluate","params":{"expression":"answer + 1"}}`))
+	msg := waitFor(t, session.Messages(), func(m message) bool { return m.ID == 1 })
+
+	var result struct {
+		Result struct {
+			Value int `json:"value"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(msg.Result, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Result.Value != 43 {
+		t.Errorf("unexpected result: %s", msg.Result)
+	}
+}
+
+func TestConnectDisposed(t *testing.T) {
+	t.Parallel()
+
+	iso := v8.NewIsolate()
+	defer iso.Dispose()
+	ins := iso.Inspector()
+	ins.Dispose()
+
+	done := make(chan struct{})
+	go func() {
+		session := ins.Connect(func([]byte) { t.Error("unexpected message") })
+		session.DispatchMessage([]byte(`{"id":1,"method":"Runtime.enable"}`))
+		session.Disconnect()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out connecting to a disposed inspector")
+	}
+}
+
+func TestSessionPause(t *testing.T) {
+	t.Parallel()
+
+	iso := v8.NewIsolate()
+	defer iso.Dispose()
+	ctx := v8.NewContext(iso)
+	defer ctx.Close()
+
+	target := inspector.Attach(ctx, "test")
+	session := target.Connect()
+	defer session.Close()
+
+	session.Send([]byte(`{"id":1,"method":"Debugger.enable"}`))
+	waitFor(t, session.Messages(), func(m message) bool { return m.ID == 1 })
+
+	type result struct {
+		val *v8.Value
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		val, err := ctx.RunScript("let x = 1;\ndebugger;\nx + 1", "paused.js")
+		done <- result{val, err}
+	}()
+
+	msg := waitFor(t, session.Messages(), func(m message) bool { return m.Method == "Debugger.paused" })
+	var paused struct {
+		CallFrames []struct {
+			CallFrameID string `json:"callFrameId"`
+			Location    struct {
+				LineNumber int `json:"lineNumber"`
+			} `json:"location"`
+		} `json:"callFrames"`
+	}
+	if err := json.Unmarshal(msg.Params, &paused); err != nil {
+		t.Fatal(err)
+	}
+	if len(paused.CallFrames) == 0 || paused.CallFrames[0].Location.LineNumber != 1 {
+		t.Fatalf("unexpected pause location: %s", msg.Params)
+	}
+	select {
+	case <-done:
+		t.Fatal("script completed while paused")
+	default:
+	}
+
+	// The isolate can be inspected while it is paused.
+	session.Send([]byte(`{"id":2,"method":"Debugger.evaluateOnCallFrame","params":{"callFrameId":"` +
+		paused.CallFrames[0].CallFrameID + `","expression":"x"}}`))
+	msg = waitFor(t, session.Messages(), func(m message) bool { return m.ID == 2 })
+	var eval struct {
+		Result struct {
+			Value int `json:"value"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(msg.Result, &eval); err != nil {
+		t.Fatal(err)
+	}
+	if eval.Result.Value != 1 {
+		t.Errorf("unexpected value of x: %s", msg.Result)
+	}
+
+	session.Send([]byte(`{"id":3,"method":"Debugger.resume"}`))
+	select {
+	case res := <-done:
+		if res.err != nil {
+			t.Fatal(res.err)
+		}
+		if res.val.Int32() != 2 {
+			t.Errorf("unexpected result %v", res.val)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("script not resumed")
+	}
+}
+
+func TestTargetServeConn(t *testing.T) {
+	t.Parallel()
+
+	iso := v8.NewIsolate()
+	defer iso.Dispose()
+	ctx := v8.NewContext(iso)
+	defer ctx.Close()
+
+	target := inspector.Attach(ctx, "test")
+	server, client := net.Pipe()
+	served := make(chan error, 1)
+	go func() { served <- target.ServeConn(server) }()
+
+	if _, err := client.Write([]byte(`{"id":7,"method":"Runtime.evaluate","params":{"expression":"'ok'"}}` + "\n")); err != nil {
+		t.Fatal(err)
+	}
+	lines := make(chan []byte)
+	go func() {
+		sc := bufio.NewScanner(client)
+		for sc.Scan() {
+			lines <- append([]byte(nil), sc.Bytes()...)
+		}
+		close(lines)
+	}()
+	msg := waitFor(t, lines, func(m message) bool { return m.ID == 7 })
+	if string(msg.Result) != `{"result":{"type":"string","value":"ok"}}` {
+		t.Errorf("unexpected result: %s", msg.Result)
+	}
+
+	client.Close()
+	if err := <-served; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestServerList(t *testing.T) {
+	t.Parallel()
+
+	iso := v8.NewIsolate()
+	defer iso.Dispose()
+	ctx := v8.NewContext(iso)
+	defer ctx.Close()
+
+	target := inspector.Attach(ctx, "my script")
+	srv := httptest.NewServer(inspector.NewServer(target))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/json/list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var list []struct {
+		ID                   string `json:"id"`
+		Title                string `json:"title"`
+		WebSocketDebuggerURL string `json:"webSocketDebuggerUrl"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ID != target.ID || list[0].Title != "my script" ||
+		list[0].WebSocketDebuggerURL != "ws://"+srv.Listener.Addr().String()+"/"+target.ID {
+		t.Errorf("unexpected targets: %+v", list)
+	}
+
+	resp, err = http.Get(srv.URL + "/unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unexpected status %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2023 the v8go contributors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package inspector
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	v8 "rogchap.com/v8go"
+)
+
+// Server serves the Targets added to it to debuggers over WebSockets, along
+// with the HTTP endpoints that Chrome DevTools (chrome://inspect) and VS Code
+// use to discover them: /json/version and /json/list.
+// Each Target is served at ws://<host>/<ID>.
+//
+// The Server should only listen on a loopback address, such as
+// 127.0.0.1:9229: debuggers can run arbitrary code in the targets.
+type Server struct {
+	mu      sync.Mutex
+	targets []*Target
+}
+
+// NewServer returns a Server for the given targets.
+func NewServer(targets ...*Target) *Server {
+	return &Server{targets: targets}
+}
+
+// Add adds a Target to the Server.
+func (s *Server) Add(t *Target) {
+	s.mu.Lock()
+	s.targets = append(s.targets, t)
+	s.mu.Unlock()
+}
+
+// Remove removes a Target from the Server; the sessions that are connected
+// to it are not closed.
+func (s *Server) Remove(t *Target) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, target := range s.targets {
+		if target == t {
+			s.targets = append(s.targets[:i], s.targets[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *Server) target(id string) *Target {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.targets {
+		if t.ID == id {
+			return t
+		}
+	}
+	return nil
+}
+
+// ListenAndServe listens on the TCP address addr and serves the debuggers
+// that connect to it.
+func (s *Server) ListenAndServe(addr string) error {
+	return http.ListenAndServe(addr, s)
+}
+
+type targetInfo struct {
+	Description          string `json:"description"`
+	DevtoolsFrontendURL  string `json:"devtoolsFrontendUrl"`
+	ID                   string `json:"id"`
+	Title                string `json:"title"`
+	Type                 string `json:"type"`
+	URL                  string `json:"url"`
+	WebSocketDebuggerURL string `json:"webSocketDebuggerUrl"`
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Guard against DNS rebinding: browsers send the host name of the page
+	// that made the request.
+	if !isLocalHost(r.Host) {
+		http.Error(w, "host not allowed", http.StatusForbidden)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/json/version":
+		writeJSON(w, map[string]string{
+			"Browser":          "v8go/" + v8.Version(),
+			"Protocol-Version": "1.3",
+			"V8-Version":       v8.Version(),
+		})
+		return
+	case "/json", "/json/list":
+		s.mu.Lock()
+		infos := make([]targetInfo, len(s.targets))
+		for i, t := range s.targets {
+			ws := r.Host + "/" + t.ID
+			infos[i] = targetInfo{
+				Description:          "v8go instance",
+				DevtoolsFrontendURL:  "devtools://devtools/bundled/js_app.html?experiments=true&v8only=true&ws=" + ws,
+				ID:                   t.ID,
+				Title:                t.Title,
+				Type:                 "node",
+				URL:                  "file://",
+				WebSocketDebuggerURL: "ws://" + ws,
+			}
+		}
+		s.mu.Unlock()
+		writeJSON(w, infos)
+		return
+	}
+
+	t := s.target(strings.TrimPrefix(r.URL.Path, "/"))
+	if t == nil || !isWebSocketUpgrade(r) {
+		http.NotFound(w, r)
+		return
+	}
+	conn, err := upgradeWebSocket(w, r)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	t.serveWebSocket(conn)
+}
+
+func (t *Target) serveWebSocket(conn *wsConn) {
+	session := t.Connect()
+	defer session.Close()
+
+	go func() {
+		for msg := range session.Messages() {
+			if err := conn.WriteMessage(msg); err != nil {
+				conn.Close()
+				return
+			}
+		}
+	}()
+
+	for {
+		msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		session.Send(msg)
+	}
+}
+
+func isLocalHost(hostport string) bool {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = hostport
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	return host == "localhost" || net.ParseIP(host) != nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(v)
+}
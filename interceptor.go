@@ -0,0 +1,267 @@
+// Copyright 2023 the v8go contributors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package v8go
+
+// #include <stdlib.h>
+// #include "v8go.h"
+import "C"
+import (
+	"runtime"
+	"unsafe"
+)
+
+// PropertyGetter intercepts reading a property. Returning nil does not
+// intercept the request, and the property is looked up on the object as usual.
+type PropertyGetter func(info *PropertyCallbackInfo) *Value
+
+// PropertySetter intercepts assigning info.Value() to a property. Returning
+// false does not intercept the request, and the property is set on the
+// object as usual.
+type PropertySetter func(info *PropertyCallbackInfo) bool
+
+// PropertyQuery intercepts checking whether a property exists, such as with
+// the `in` operator, returning the attributes of the property and true if it
+// exists. Returning false does not intercept the request.
+type PropertyQuery func(info *PropertyCallbackInfo) (PropertyAttribute, bool)
+
+// PropertyDeleter intercepts deleting a property. Returning false does not
+// intercept the request, and the property is deleted from the object as usual.
+type PropertyDeleter func(info *PropertyCallbackInfo) bool
+
+// NamedPropertyEnumerator returns the names of the intercepted properties,
+// such as for `Object.keys` or a `for...in` loop.
+type NamedPropertyEnumerator func(info *PropertyCallbackInfo) []string
+
+// IndexedPropertyEnumerator returns the indices of the intercepted properties.
+type IndexedPropertyEnumerator func(info *PropertyCallbackInfo) []uint32
+
+// NamedPropertyHandler holds the callbacks that intercept access to the
+// properties of an object by name, see ObjectTemplate.SetNamedPropertyHandler.
+// Only Getter is required.
+type NamedPropertyHandler struct {
+	Getter     PropertyGetter
+	Setter     PropertySetter
+	Query      PropertyQuery
+	Deleter    PropertyDeleter
+	Enumerator NamedPropertyEnumerator
+}
+
+// IndexedPropertyHandler holds the callbacks that intercept access to the
+// properties of an object by index, see ObjectTemplate.SetIndexedPropertyHandler.
+// Only Getter is required.
+type IndexedPropertyHandler struct {
+	Getter     PropertyGetter
+	Setter     PropertySetter
+	Query      PropertyQuery
+	Deleter    PropertyDeleter
+	Enumerator IndexedPropertyEnumerator
+}
+
+// PropertyHandler is a NamedPropertyHandler or an IndexedPropertyHandler, see
+// WithStartupPropertyHandlers.
+type PropertyHandler interface {
+	propertyHandler() *propertyHandler
+}
+
+func (h NamedPropertyHandler) propertyHandler() *propertyHandler {
+	return &propertyHandler{
+		getter:          h.Getter,
+		setter:          h.Setter,
+		query:           h.Query,
+		deleter:         h.Deleter,
+		namedEnumerator: h.Enumerator,
+	}
+}
+
+func (h IndexedPropertyHandler) propertyHandler() *propertyHandler {
+	return &propertyHandler{
+		getter:            h.Getter,
+		setter:            h.Setter,
+		query:             h.Query,
+		deleter:           h.Deleter,
+		indexedEnumerator: h.Enumerator,
+	}
+}
+
+type propertyHandler struct {
+	getter            PropertyGetter
+	setter            PropertySetter
+	query             PropertyQuery
+	deleter           PropertyDeleter
+	namedEnumerator   NamedPropertyEnumerator
+	indexedEnumerator IndexedPropertyEnumerator
+}
+
+func (h *propertyHandler) callbacks() C.int {
+	var cbs C.int
+	if h.setter != nil {
+		cbs |= C.PropertyHandlerSetter
+	}
+	if h.query != nil {
+		cbs |= C.PropertyHandlerQuery
+	}
+	if h.deleter != nil {
+		cbs |= C.PropertyHandlerDeleter
+	}
+	if h.namedEnumerator != nil || h.indexedEnumerator != nil {
+		cbs |= C.PropertyHandlerEnumerator
+	}
+	return cbs
+}
+
+// SetNamedPropertyHandler intercepts access by name to the properties of each
+// instance created by this template, calling the Go callbacks of the handler.
+// Symbol properties are not intercepted.
+func (o *ObjectTemplate) SetNamedPropertyHandler(handler NamedPropertyHandler) {
+	if handler.Getter == nil {
+		panic("nil PropertyGetter argument not supported")
+	}
+	h := handler.propertyHandler()
+	cbref := o.iso.registerPropertyHandler(h)
+	C.ObjectTemplateSetNamedPropertyHandler(o.ptr, C.int(cbref), h.callbacks())
+	runtime.KeepAlive(o)
+}
+
+// SetIndexedPropertyHandler intercepts access by index to the properties of
+// each instance created by this template, calling the Go callbacks of the handler.
+func (o *ObjectTemplate) SetIndexedPropertyHandler(handler IndexedPropertyHandler) {
+	if handler.Getter == nil {
+		panic("nil PropertyGetter argument not supported")
+	}
+	h := handler.propertyHandler()
+	cbref := o.iso.registerPropertyHandler(h)
+	C.ObjectTemplateSetIndexedPropertyHandler(o.ptr, C.int(cbref), h.callbacks())
+	runtime.KeepAlive(o)
+}
+
+func (i *Isolate) registerPropertyHandler(h *propertyHandler) int {
+	i.cbMutex.Lock()
+	i.handlerSeq++
+	ref := i.handlerSeq
+	i.handlers[ref] = h
+	i.cbMutex.Unlock()
+	return ref
+}
+
+// getPropertyHandler returns the handler for ref; it is empty if the handler
+// was restored from a snapshot without WithStartupPropertyHandlers, so that
+// no request is intercepted.
+func (i *Isolate) getPropertyHandler(ref int) *propertyHandler {
+	i.cbMutex.RLock()
+	defer i.cbMutex.RUnlock()
+	if h := i.handlers[ref]; h != nil {
+		return h
+	}
+	return &propertyHandler{}
+}
+
+// newInterceptorInfo returns the PropertyCallbackInfo for a named property
+// when key is not nil, or else for an indexed property.
+func newInterceptorInfo(ctx *Context, thisPtr C.ValuePtr, key *C.char, index C.uint32_t) *PropertyCallbackInfo {
+	info := &PropertyCallbackInfo{
+		ctx:   ctx,
+		this:  &Object{&Value{ptr: thisPtr, ctx: ctx}},
+		index: uint32(index),
+	}
+	if key != nil {
+		info.key = C.GoString(key)
+	}
+	return info
+}
+
+//export goPropertyHandlerGetter
+func goPropertyHandlerGetter(ctxref int, cbref int, thisPtr C.ValuePtr, key *C.char, index C.uint32_t) C.ValuePtr {
+	ctx := getContext(ctxref)
+	info := newInterceptorInfo(ctx, thisPtr, key, index)
+
+	getter := ctx.iso.getPropertyHandler(cbref).getter
+	if getter == nil {
+		return nil
+	}
+	if val := getter(info); val != nil {
+		return val.ptr
+	}
+	return nil
+}
+
+//export goPropertyHandlerSetter
+func goPropertyHandlerSetter(ctxref int, cbref int, thisPtr C.ValuePtr, key *C.char, index C.uint32_t, value C.ValuePtr) C.int {
+	ctx := getContext(ctxref)
+	info := newInterceptorInfo(ctx, thisPtr, key, index)
+	info.value = &Value{ptr: value, ctx: ctx}
+
+	setter := ctx.iso.getPropertyHandler(cbref).setter
+	return boolToCInt(setter != nil && setter(info))
+}
+
+//export goPropertyHandlerQuery
+func goPropertyHandlerQuery(ctxref int, cbref int, thisPtr C.ValuePtr, key *C.char, index C.uint32_t) C.int {
+	ctx := getContext(ctxref)
+	info := newInterceptorInfo(ctx, thisPtr, key, index)
+
+	query := ctx.iso.getPropertyHandler(cbref).query
+	if query == nil {
+		return -1
+	}
+	attrs, ok := query(info)
+	if !ok {
+		return -1
+	}
+	return C.int(attrs)
+}
+
+//export goPropertyHandlerDeleter
+func goPropertyHandlerDeleter(ctxref int, cbref int, thisPtr C.ValuePtr, key *C.char, index C.uint32_t) C.int {
+	ctx := getContext(ctxref)
+	info := newInterceptorInfo(ctx, thisPtr, key, index)
+
+	deleter := ctx.iso.getPropertyHandler(cbref).deleter
+	return boolToCInt(deleter != nil && deleter(info))
+}
+
+// The arrays returned by the enumerators are allocated with malloc and freed
+// by the caller, along with the strings they hold.
+
+//export goNamedPropertyEnumerator
+func goNamedPropertyEnumerator(ctxref int, cbref int, thisPtr C.ValuePtr) (**C.char, C.int) {
+	ctx := getContext(ctxref)
+	info := newInterceptorInfo(ctx, thisPtr, nil, 0)
+
+	enumerator := ctx.iso.getPropertyHandler(cbref).namedEnumerator
+	if enumerator == nil {
+		return nil, 0
+	}
+	keys := enumerator(info)
+	if len(keys) == 0 {
+		return nil, 0
+	}
+	ptr := (**C.char)(C.malloc(C.size_t(len(keys)) * C.size_t(unsafe.Sizeof((*C.char)(nil)))))
+	arr := unsafe.Slice(ptr, len(keys))
+	for i, k := range keys {
+		arr[i] = C.CString(k)
+	}
+	return ptr, C.int(len(keys))
+}
+
+//export goIndexedPropertyEnumerator
+func goIndexedPropertyEnumerator(ctxref int, cbref int, thisPtr C.ValuePtr) (*C.uint32_t, C.int) {
+	ctx := getContext(ctxref)
+	info := newInterceptorInfo(ctx, thisPtr, nil, 0)
+
+	enumerator := ctx.iso.getPropertyHandler(cbref).indexedEnumerator
+	if enumerator == nil {
+		return nil, 0
+	}
+	indices := enumerator(info)
+	if len(indices) == 0 {
+		return nil, 0
+	}
+	ptr := (*C.uint32_t)(C.malloc(C.size_t(len(indices)) * C.size_t(unsafe.Sizeof(C.uint32_t(0)))))
+	arr := unsafe.Slice(ptr, len(indices))
+	for i, idx := range indices {
+		arr[i] = C.uint32_t(idx)
+	}
+	return ptr, C.int(len(indices))
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 the v8go contributors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package v8go_test
+
+import (
+	"sort"
+	"testing"
+
+	v8 "rogchap.com/v8go"
+)
+
+func TestObjectTemplateNamedPropertyHandler(t *testing.T) {
+	t.Parallel()
+	iso := v8.NewIsolate()
+	defer iso.Dispose()
+
+	store := map[string]string{"host": "localhost", "port": "8080"}
+
+	tmpl := v8.NewObjectTemplate(iso)
+	tmpl.SetNamedPropertyHandler(v8.NamedPropertyHandler{
+		Getter: func(info *v8.PropertyCallbackInfo) *v8.Value {
+			v, ok := store[info.Key()]
+			if !ok {
+				return nil
+			}
+			val, _ := v8.NewValue(iso, v)
+			return val
+		},
+		Setter: func(info *v8.PropertyCallbackInfo) bool {
+			store[info.Key()] = info.Value().String()
+			return true
+		},
+		Query: func(info *v8.PropertyCallbackInfo) (v8.PropertyAttribute, bool) {
+			_, ok := store[info.Key()]
+			return v8.None, ok
+		},
+		Deleter: func(info *v8.PropertyCallbackInfo) bool {
+			delete(store, info.Key())
+			return true
+		},
+		Enumerator: func(info *v8.PropertyCallbackInfo) []string {
+			keys := make([]string, 0, len(store))
+			for k := range store {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			return keys
+		},
+	})
+
+	ctx := v8.NewContext(iso)
+	defer ctx.Close()
+	obj, err := tmpl.NewInstance(ctx)
+	fatalIf(t, err)
+	fatalIf(t, ctx.Global().Set("config", obj))
+
+	val, err := ctx.RunScript(`
+		config.user = "admin";
+		delete config.port;
+		[config.host, "port" in config, "user" in config, Object.keys(config).join(), String(config.toString === Object.prototype.toString)].join(" ")
+	`, "")
+	fatalIf(t, err)
+	if s := val.String(); s != "localhost false true host,user true" {
+		t.Errorf("unexpected value: %q", s)
+	}
+	if _, ok := store["port"]; ok || store["user"] != "admin" {
+		t.Errorf("unexpected store: %v", store)
+	}
+}
+
+func TestObjectTemplateIndexedPropertyHandler(t *testing.T) {
+	t.Parallel()
+	iso := v8.NewIsolate()
+	defer iso.Dispose()
+
+	rows := []int32{10, 20, 30}
+
+	tmpl := v8.NewObjectTemplate(iso)
+	tmpl.SetIndexedPropertyHandler(v8.IndexedPropertyHandler{
+		Getter: func(info *v8.PropertyCallbackInfo) *v8.Value {
+			if int(info.Index()) >= len(rows) {
+				return nil
+			}
+			val, _ := v8.NewValue(iso, rows[info.Index()])
+			return val
+		},
+		Setter: func(info *v8.PropertyCallbackInfo) bool {
+			if int(info.Index()) >= len(rows) {
+				return false
+			}
+			rows[info.Index()] = info.Value().Int32()
+			return true
+		},
+		Enumerator: func(info *v8.PropertyCallbackInfo) []uint32 {
+			indices := make([]uint32, len(rows))
+			for i := range rows {
+				indices[i] = uint32(i)
+			}
+			return indices
+		},
+	})
+	fatalIf(t, tmpl.Set("length", int32(len(rows))))
+
+	ctx := v8.NewContext(iso)
+	defer ctx.Close()
+	obj, err := tmpl.NewInstance(ctx)
+	fatalIf(t, err)
+	fatalIf(t, ctx.Global().Set("rows", obj))
+
+	val, err := ctx.RunScript(`
+		const keys = Object.keys(rows).join();
+		rows[1] = 21;
+		rows[5] = 60;
+		[Array.prototype.join.call(rows), keys, rows[5]].join(" ")
+	`, "")
+	fatalIf(t, err)
+	if s := val.String(); s != "10,21,30 0,1,2,length 60" {
+		t.Errorf("unexpected value: %q", s)
+	}
+}
@@ -9,24 +9,115 @@ package v8go
 import "C"
 
 import (
+	"context"
+	"regexp"
 	"sync"
 	"unsafe"
 )
 
 var v8once sync.Once
 
+// As with contexts, isolates are kept in a registry so that they can be looked
+// up by reference from any callback from V8.
+var isoMutex sync.RWMutex
+var isoRegistry = make(map[int]*Isolate)
+var isoSeq = 0
+
 // Isolate is a JavaScript VM instance with its own heap and
 // garbage collector. Most applications will create one isolate
 // with many V8 contexts for execution.
 type Isolate struct {
+	ref int
 	ptr C.IsolatePtr
 
 	cbMutex sync.RWMutex
 	cbSeq   int
 	cbs     map[int]FunctionCallback
+	// Accessors and handlers have their own sequences so that those of a
+	// snapshot can be restored in order, as with function callbacks.
+	accessorSeq int
+	accessors   map[int]Accessor
+	handlerSeq  int
+	handlers    map[int]*propertyHandler
 
 	null      *Value
 	undefined *Value
+
+	constraints *ResourceConstraints
+
+	consoleDelegate ConsoleDelegate
+	inspector       *Inspector
+	coverage        *coverageSession
+	// insMutex serializes the creation of the inspector.
+	insMutex sync.Mutex
+
+	// The callbacks called during garbage collection have their own mutex,
+	// which is never held while calling into V8, so that the allocations of
+	// any call can run them.
+	gcMutex         sync.RWMutex
+	nearHeapLimitCb NearHeapLimitCallback
+	gcPrologueCb    GCCallback
+	gcEpilogueCb    GCCallback
+
+	modMutex sync.RWMutex
+	modules  map[C.ModulePtr]*Module
+
+	dynamicImportCb DynamicImportCallback
+	importMetaCb    ImportMetaCallback
+
+	smMutex     sync.RWMutex
+	sourceMaps  map[string]*SourceMap
+	smLocations *regexp.Regexp
+
+	// snapshotCreator is set when the Isolate is owned by a SnapshotCreator.
+	snapshotCreator *SnapshotCreator
+}
+
+// NearHeapLimitCallback is called when the heap of an Isolate is close to its
+// limit and garbage collection could not free enough memory. It receives the
+// current and initial heap limits in bytes and returns the new heap limit.
+// Returning a limit that is not greater than currentLimit terminates the
+// execution, which then fails with ErrHeapLimitExceeded.
+// The callback is called during garbage collection and must not call back
+// into the Isolate.
+type NearHeapLimitCallback func(currentLimit, initialLimit uint64) uint64
+
+// IsolateOption configures an Isolate created with NewIsolate.
+type IsolateOption func(*isolateOptions)
+
+type isolateOptions struct {
+	constraints *ResourceConstraints
+	startupData *StartupData
+	callbacks   []FunctionCallback
+	accessors   []Accessor
+	handlers    []PropertyHandler
+}
+
+// ResourceConstraints limits the size of the heap of an Isolate.
+// All sizes are in bytes; a zero value leaves V8's default in place.
+type ResourceConstraints struct {
+	// InitialHeapSize and MaxHeapSize configure the young and old generation
+	// sizes from a total heap size, using V8's heuristics. When the heap approaches
+	// MaxHeapSize V8 will perform a series of garbage collections and, if these do
+	// not help, abort the process with an out-of-memory error.
+	InitialHeapSize uint64
+	MaxHeapSize     uint64
+
+	// The generation sizes override the values derived from MaxHeapSize.
+	MaxOldGenerationSize       uint64
+	MaxYoungGenerationSize     uint64
+	InitialOldGenerationSize   uint64
+	InitialYoungGenerationSize uint64
+
+	// CodeRangeSize is the amount of virtual memory reserved for generated code.
+	CodeRangeSize uint64
+}
+
+// WithResourceConstraints sets the heap limits of the new Isolate.
+func WithResourceConstraints(rc ResourceConstraints) IsolateOption {
+	return func(opts *isolateOptions) {
+		opts.constraints = &rc
+	}
 }
 
 // HeapStatistics represents V8 isolate heap statistics
@@ -42,6 +133,42 @@ type HeapStatistics struct {
 	PeakMallocedMemory       uint64
 	NumberOfNativeContexts   uint64
 	NumberOfDetachedContexts uint64
+
+	// The resource constraints the isolate was created with, after V8 has
+	// derived the generation sizes; zero if created without WithResourceConstraints.
+	MaxOldGenerationSize       uint64
+	MaxYoungGenerationSize     uint64
+	InitialOldGenerationSize   uint64
+	InitialYoungGenerationSize uint64
+	CodeRangeSize              uint64
+}
+
+// HeapSpaceStatistics represents the statistics of a space of the heap of an
+// isolate, such as new_space, old_space, code_space or large_object_space.
+type HeapSpaceStatistics struct {
+	SpaceName          string
+	SpaceSize          uint64
+	SpaceUsedSize      uint64
+	SpaceAvailableSize uint64
+	PhysicalSpaceSize  uint64
+}
+
+// HeapCodeStatistics represents the statistics of the code of an isolate and
+// its metadata.
+type HeapCodeStatistics struct {
+	CodeAndMetadataSize      uint64
+	BytecodeAndMetadataSize  uint64
+	ExternalScriptSourceSize uint64
+	CPUProfilerMetadataSize  uint64
+}
+
+// HeapObjectStatistics represents the count and size of the objects of a
+// type that were live at the last garbage collection of an isolate.
+type HeapObjectStatistics struct {
+	ObjectType    string
+	ObjectSubType string
+	ObjectCount   uint64
+	ObjectSize    uint64
 }
 
 // NewIsolate creates a new V8 isolate. Only one thread may access
@@ -51,23 +178,112 @@ type HeapStatistics struct {
 // by calling iso.Dispose().
 // An *Isolate can be used as a v8go.ContextOption to create a new
 // Context, rather than creating a new default Isolate.
-func NewIsolate() *Isolate {
+// IsolateOptions such as WithResourceConstraints configure the new isolate.
+func NewIsolate(opt ...IsolateOption) *Isolate {
 	initializeIfNecessary()
+	opts := isolateOptions{}
+	for _, o := range opt {
+		if o != nil {
+			o(&opts)
+		}
+	}
+
+	var cConstraints *C.IsolateConstraints
+	if rc := opts.constraints; rc != nil {
+		cConstraints = &C.IsolateConstraints{
+			initial_heap_size:             C.size_t(rc.InitialHeapSize),
+			max_heap_size:                 C.size_t(rc.MaxHeapSize),
+			max_old_generation_size:       C.size_t(rc.MaxOldGenerationSize),
+			max_young_generation_size:     C.size_t(rc.MaxYoungGenerationSize),
+			initial_old_generation_size:   C.size_t(rc.InitialOldGenerationSize),
+			initial_young_generation_size: C.size_t(rc.InitialYoungGenerationSize),
+			code_range_size:               C.size_t(rc.CodeRangeSize),
+		}
+	}
+
+	var cBlob *C.char
+	var cBlobSize C.int
+	if sd := opts.startupData; sd != nil && len(sd.Bytes) > 0 {
+		cBlob = (*C.char)(unsafe.Pointer(&sd.Bytes[0]))
+		cBlobSize = C.int(len(sd.Bytes))
+	}
+
+	iso := newIsolate()
+	iso.ptr = C.NewIsolate(cConstraints, cBlob, cBlobSize, C.int(iso.ref))
+	if cConstraints != nil {
+		iso.constraints = &ResourceConstraints{
+			InitialHeapSize:            opts.constraints.InitialHeapSize,
+			MaxHeapSize:                opts.constraints.MaxHeapSize,
+			MaxOldGenerationSize:       uint64(cConstraints.max_old_generation_size),
+			MaxYoungGenerationSize:     uint64(cConstraints.max_young_generation_size),
+			InitialOldGenerationSize:   uint64(cConstraints.initial_old_generation_size),
+			InitialYoungGenerationSize: uint64(cConstraints.initial_young_generation_size),
+			CodeRangeSize:              uint64(cConstraints.code_range_size),
+		}
+	}
+	for _, cb := range opts.callbacks {
+		iso.registerCallback(cb)
+	}
+	for _, a := range opts.accessors {
+		iso.registerAccessor(a)
+	}
+	for _, h := range opts.handlers {
+		iso.registerPropertyHandler(h.propertyHandler())
+	}
+	iso.init()
+	return iso
+}
+
+// newIsolate allocates and registers an Isolate; its ptr must be set before
+// calling init.
+func newIsolate() *Isolate {
+	isoMutex.Lock()
+	isoSeq++
+	ref := isoSeq
+	isoMutex.Unlock()
+
 	iso := &Isolate{
-		ptr: C.NewIsolate(),
-		cbs: make(map[int]FunctionCallback),
+		ref:       ref,
+		cbs:       make(map[int]FunctionCallback),
+		accessors: make(map[int]Accessor),
+		handlers:  make(map[int]*propertyHandler),
+		modules:   make(map[C.ModulePtr]*Module),
 	}
-	iso.null = newValueNull(iso)
-	iso.undefined = newValueUndefined(iso)
+	iso.register()
 	return iso
 }
 
+func (i *Isolate) init() {
+	i.null = newValueNull(i)
+	i.undefined = newValueUndefined(i)
+}
+
 // TerminateExecution terminates forcefully the current thread
-// of JavaScript execution in the given isolate.
+// of JavaScript execution in the given isolate. The terminated call fails
+// with an error that matches ErrExecutionTerminated.
 func (i *Isolate) TerminateExecution() {
 	C.IsolateTerminateExecution(i.ptr)
 }
 
+// CancelTerminateExecution resumes execution that is being terminated, or
+// cancels a termination requested while no JavaScript was running, which
+// would otherwise terminate the next execution. The Isolate can then be
+// used again, eg. after a timeout.
+func (i *Isolate) CancelTerminateExecution() {
+	C.IsolateCancelTerminateExecution(i.ptr)
+}
+
+// SetNearHeapLimitCallback sets the callback that decides whether the heap
+// limit is raised when it is about to be reached. Without a callback, or
+// after setting it to nil, execution is terminated and fails with
+// ErrHeapLimitExceeded instead of aborting the process.
+// Once the heap limit has been exceeded the Isolate should be disposed.
+func (i *Isolate) SetNearHeapLimitCallback(cb NearHeapLimitCallback) {
+	i.gcMutex.Lock()
+	i.nearHeapLimitCb = cb
+	i.gcMutex.Unlock()
+}
+
 // IsExecutionTerminating returns whether V8 is currently terminating
 // Javascript execution. If true, there are still JavaScript frames
 // on the stack and the termination exception is still active.
@@ -87,6 +303,7 @@ type CompileOptions struct {
 // that code cache.
 // error will be of type `JSError` if not nil.
 func (i *Isolate) CompileUnboundScript(source, origin string, opts CompileOptions) (*UnboundScript, error) {
+	i.recordCoverageSource(origin, source)
 	cSource := C.CString(source)
 	cOrigin := C.CString(origin)
 	defer C.free(unsafe.Pointer(cSource))
@@ -123,7 +340,7 @@ func (i *Isolate) CompileUnboundScript(source, origin string, opts CompileOption
 func (i *Isolate) GetHeapStatistics() HeapStatistics {
 	hs := C.IsolationGetHeapStatistics(i.ptr)
 
-	return HeapStatistics{
+	stats := HeapStatistics{
 		TotalHeapSize:            uint64(hs.total_heap_size),
 		TotalHeapSizeExecutable:  uint64(hs.total_heap_size_executable),
 		TotalPhysicalSize:        uint64(hs.total_physical_size),
@@ -136,15 +353,88 @@ func (i *Isolate) GetHeapStatistics() HeapStatistics {
 		NumberOfNativeContexts:   uint64(hs.number_of_native_contexts),
 		NumberOfDetachedContexts: uint64(hs.number_of_detached_contexts),
 	}
+	if rc := i.constraints; rc != nil {
+		stats.MaxOldGenerationSize = rc.MaxOldGenerationSize
+		stats.MaxYoungGenerationSize = rc.MaxYoungGenerationSize
+		stats.InitialOldGenerationSize = rc.InitialOldGenerationSize
+		stats.InitialYoungGenerationSize = rc.InitialYoungGenerationSize
+		stats.CodeRangeSize = rc.CodeRangeSize
+	}
+	return stats
+}
+
+// GetHeapSpaceStatistics returns the statistics of each space of the heap of
+// the isolate.
+func (i *Isolate) GetHeapSpaceStatistics() []HeapSpaceStatistics {
+	n := C.IsolateNumberOfHeapSpaces(i.ptr)
+	stats := make([]HeapSpaceStatistics, 0, n)
+	for idx := C.size_t(0); idx < n; idx++ {
+		hs := C.IsolateGetHeapSpaceStatistics(i.ptr, idx)
+		if hs.space_name == nil {
+			continue
+		}
+		stats = append(stats, HeapSpaceStatistics{
+			SpaceName:          C.GoString(hs.space_name),
+			SpaceSize:          uint64(hs.space_size),
+			SpaceUsedSize:      uint64(hs.space_used_size),
+			SpaceAvailableSize: uint64(hs.space_available_size),
+			PhysicalSpaceSize:  uint64(hs.physical_space_size),
+		})
+	}
+	return stats
+}
+
+// GetHeapCodeStatistics returns the statistics of the code of the isolate:
+// the compiled code, the bytecode and the sources of the scripts. Unlike
+// GetHeapStatistics, it locks the isolate, so it blocks while a script runs.
+func (i *Isolate) GetHeapCodeStatistics() HeapCodeStatistics {
+	hs := C.IsolateGetHeapCodeStatistics(i.ptr)
+	return HeapCodeStatistics{
+		CodeAndMetadataSize:      uint64(hs.code_and_metadata_size),
+		BytecodeAndMetadataSize:  uint64(hs.bytecode_and_metadata_size),
+		ExternalScriptSourceSize: uint64(hs.external_script_source_size),
+		CPUProfilerMetadataSize:  uint64(hs.cpu_profiler_metadata_size),
+	}
+}
+
+// GetHeapObjectStatistics returns the count and size of the objects of each
+// type that were live at the last garbage collection, omitting the types
+// without objects. The statistics are only tracked when V8 is started with
+// the flag --track-gc-object-stats, see SetFlags; otherwise it returns nil.
+// It locks the isolate, so it blocks while a script runs.
+func (i *Isolate) GetHeapObjectStatistics() []HeapObjectStatistics {
+	var stats []HeapObjectStatistics
+	n := C.IsolateNumberOfTrackedHeapObjectTypes(i.ptr)
+	for idx := C.size_t(0); idx < n; idx++ {
+		hs := C.IsolateGetHeapObjectStatistics(i.ptr, idx)
+		if hs.object_type == nil || hs.object_count == 0 {
+			continue
+		}
+		stats = append(stats, HeapObjectStatistics{
+			ObjectType:    C.GoString(hs.object_type),
+			ObjectSubType: C.GoString(hs.object_sub_type),
+			ObjectCount:   uint64(hs.object_count),
+			ObjectSize:    uint64(hs.object_size),
+		})
+	}
+	return stats
 }
 
 // Dispose will dispose the Isolate VM; subsequent calls will panic.
+// An Isolate owned by a SnapshotCreator is disposed by the SnapshotCreator.
 func (i *Isolate) Dispose() {
 	if i.ptr == nil {
 		return
 	}
+	if i.snapshotCreator != nil {
+		panic("v8go: Isolate is owned by a SnapshotCreator, use SnapshotCreator.Dispose")
+	}
+	if ins := i.getInspector(); ins != nil {
+		ins.Dispose()
+	}
 	C.IsolateDispose(i.ptr)
 	i.ptr = nil
+	i.deregister()
 }
 
 // ThrowException schedules an exception to be thrown when returning to
@@ -183,3 +473,69 @@ func (i *Isolate) getCallback(ref int) FunctionCallback {
 	defer i.cbMutex.RUnlock()
 	return i.cbs[ref]
 }
+
+// runWithContext runs fn, terminating the execution of the isolate if ctx is
+// done before fn returns. If the execution was terminated the returned error
+// wraps ctx.Err().
+func (i *Isolate) runWithContext(ctx context.Context, fn func() (*Value, error)) (*Value, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	terminated := make(chan bool, 1)
+	go func() {
+		select {
+		case <-ctx.Done():
+			i.TerminateExecution()
+			terminated <- true
+		case <-done:
+			terminated <- false
+		}
+	}()
+
+	val, err := fn()
+	close(done)
+	if <-terminated {
+		// The termination may have been requested after fn returned, in which
+		// case it would terminate the next execution in this isolate instead.
+		i.CancelTerminateExecution()
+		if err != nil {
+			return nil, &terminatedError{reason: ctx.Err()}
+		}
+	}
+	return val, err
+}
+
+func (i *Isolate) register() {
+	isoMutex.Lock()
+	isoRegistry[i.ref] = i
+	isoMutex.Unlock()
+}
+
+func (i *Isolate) deregister() {
+	isoMutex.Lock()
+	delete(isoRegistry, i.ref)
+	isoMutex.Unlock()
+}
+
+func getIsolate(ref int) *Isolate {
+	isoMutex.RLock()
+	defer isoMutex.RUnlock()
+	return isoRegistry[ref]
+}
+
+//export goNearHeapLimitCallback
+func goNearHeapLimitCallback(ref int, currentLimit, initialLimit C.size_t) C.size_t {
+	iso := getIsolate(ref)
+	if iso == nil {
+		return 0
+	}
+	iso.gcMutex.RLock()
+	cb := iso.nearHeapLimitCb
+	iso.gcMutex.RUnlock()
+	if cb == nil {
+		return 0
+	}
+	return C.size_t(cb(uint64(currentLimit), uint64(initialLimit)))
+}
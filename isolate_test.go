@@ -6,10 +6,12 @@ package v8go_test
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/rand"
 	"strings"
 	"testing"
+	"time"
 
 	v8 "rogchap.com/v8go"
 )
@@ -52,6 +54,40 @@ func TestIsolateTerminateExecution(t *testing.T) {
 	}
 }
 
+func TestIsolateCancelTerminateExecution(t *testing.T) {
+	t.Parallel()
+	iso := v8.NewIsolate()
+	defer iso.Dispose()
+	ctx := v8.NewContext(iso)
+	defer ctx.Close()
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		iso.TerminateExecution()
+	}()
+	_, err := ctx.RunScript(`while (true) {}`, "forever.js")
+	if !errors.Is(err, v8.ErrExecutionTerminated) {
+		t.Fatalf("expected ErrExecutionTerminated, got %v", err)
+	}
+	var jsErr *v8.JSError
+	if !errors.As(err, &jsErr) {
+		t.Errorf("expected a JSError, got %T", err)
+	}
+	if _, err := ctx.RunScript(`throw new Error("oops")`, ""); errors.Is(err, v8.ErrExecutionTerminated) {
+		t.Error("expected other errors not to match ErrExecutionTerminated")
+	}
+
+	// a termination requested while no JavaScript is running would terminate
+	// the next execution unless cancelled
+	iso.TerminateExecution()
+	iso.CancelTerminateExecution()
+	val, err := ctx.RunScript(`1 + 1`, "add.js")
+	fatalIf(t, err)
+	if val.Int32() != 2 {
+		t.Errorf("unexpected value: %v", val)
+	}
+}
+
 func TestIsolateCompileUnboundScript(t *testing.T) {
 	s := "function foo() { return 'bar'; }; foo()"
 
@@ -159,6 +195,129 @@ func TestIsolateGetHeapStatistics(t *testing.T) {
 	}
 }
 
+func TestIsolateGetHeapSpaceStatistics(t *testing.T) {
+	t.Parallel()
+	iso := v8.NewIsolate()
+	defer iso.Dispose()
+	ctx := v8.NewContext(iso)
+	defer ctx.Close()
+	_, err := ctx.RunScript("function f() { return 1 }; f()", "stats.js")
+	fatalIf(t, err)
+
+	spaces := make(map[string]v8.HeapSpaceStatistics)
+	var used uint64
+	for _, s := range iso.GetHeapSpaceStatistics() {
+		spaces[s.SpaceName] = s
+		used += s.SpaceUsedSize
+		if s.SpaceUsedSize > s.SpaceSize {
+			t.Errorf("space %s uses more than its size: %+v", s.SpaceName, s)
+		}
+	}
+	for _, name := range []string{"new_space", "old_space", "code_space", "large_object_space"} {
+		if _, ok := spaces[name]; !ok {
+			t.Errorf("expected %s in %v", name, spaces)
+		}
+	}
+	if used == 0 {
+		t.Error("expected the spaces to be used")
+	}
+
+	// the script has been compiled to bytecode
+	if cs := iso.GetHeapCodeStatistics(); cs.BytecodeAndMetadataSize == 0 {
+		t.Errorf("unexpected code statistics %+v", cs)
+	}
+
+	// object statistics are only tracked with --track-gc-object-stats
+	if stats := iso.GetHeapObjectStatistics(); stats != nil {
+		t.Errorf("expected no object statistics without the flag, got %+v", stats)
+	}
+}
+
+func TestIsolateResourceConstraints(t *testing.T) {
+	t.Parallel()
+	iso := v8.NewIsolate(v8.WithResourceConstraints(v8.ResourceConstraints{
+		MaxOldGenerationSize:   32 << 20,
+		MaxYoungGenerationSize: 4 << 20,
+	}))
+	defer iso.Dispose()
+
+	hs := iso.GetHeapStatistics()
+	if hs.MaxOldGenerationSize != 32<<20 {
+		t.Errorf("expected MaxOldGenerationSize of %d, got %d", 32<<20, hs.MaxOldGenerationSize)
+	}
+	if hs.MaxYoungGenerationSize != 4<<20 {
+		t.Errorf("expected MaxYoungGenerationSize of %d, got %d", 4<<20, hs.MaxYoungGenerationSize)
+	}
+	if hs.HeapSizeLimit == 0 || hs.HeapSizeLimit > 64<<20 {
+		t.Errorf("expected HeapSizeLimit to reflect the constraints, got %d", hs.HeapSizeLimit)
+	}
+
+	iso2 := v8.NewIsolate(v8.WithResourceConstraints(v8.ResourceConstraints{
+		MaxHeapSize: 64 << 20,
+	}))
+	defer iso2.Dispose()
+
+	hs = iso2.GetHeapStatistics()
+	if hs.MaxOldGenerationSize == 0 || hs.MaxYoungGenerationSize == 0 {
+		t.Errorf("expected generation sizes to be derived from MaxHeapSize, got %d and %d", hs.MaxOldGenerationSize, hs.MaxYoungGenerationSize)
+	}
+}
+
+func TestIsolateHeapLimitExceeded(t *testing.T) {
+	t.Parallel()
+	iso := v8.NewIsolate(v8.WithResourceConstraints(v8.ResourceConstraints{
+		MaxOldGenerationSize: 16 << 20,
+	}))
+	defer iso.Dispose()
+	ctx := v8.NewContext(iso)
+	defer ctx.Close()
+
+	script := `const a = []; while (true) { a.push(new Array(1024).fill(1)); }`
+	_, err := ctx.RunScript(script, "oom.js")
+	if !errors.Is(err, v8.ErrHeapLimitExceeded) {
+		t.Errorf("expected ErrHeapLimitExceeded, got %v", err)
+	}
+
+	// a later termination is not reported as exceeding the heap limit
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		iso.TerminateExecution()
+	}()
+	_, err = ctx.RunScript(`while (true) {}`, "forever.js")
+	if !errors.Is(err, v8.ErrExecutionTerminated) || errors.Is(err, v8.ErrHeapLimitExceeded) {
+		t.Errorf("expected ErrExecutionTerminated, got %v", err)
+	}
+}
+
+func TestIsolateNearHeapLimitCallback(t *testing.T) {
+	t.Parallel()
+	iso := v8.NewIsolate(v8.WithResourceConstraints(v8.ResourceConstraints{
+		MaxOldGenerationSize: 16 << 20,
+	}))
+	defer iso.Dispose()
+
+	var calls int
+	iso.SetNearHeapLimitCallback(func(current, initial uint64) uint64 {
+		calls++
+		if calls > 1 {
+			return current
+		}
+		return current + 8<<20
+	})
+
+	ctx := v8.NewContext(iso)
+	defer ctx.Close()
+
+	script := `const a = []; while (true) { a.push(new Array(1024).fill(1)); }`
+	_, err := ctx.RunScript(script, "oom.js")
+	if !errors.Is(err, v8.ErrHeapLimitExceeded) {
+		t.Errorf("expected ErrHeapLimitExceeded, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected callback to be called twice, got %d", calls)
+	}
+}
+
 func TestCallbackRegistry(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,273 @@
+// Copyright 2023 the v8go contributors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package v8go
+
+// #include <stdlib.h>
+// #include "v8go.h"
+import "C"
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+// ModuleStatus is the state of a Module.
+type ModuleStatus int
+
+const (
+	ModuleUninstantiated ModuleStatus = iota
+	ModuleInstantiating
+	ModuleInstantiated
+	ModuleEvaluating
+	ModuleEvaluated
+	ModuleErrored
+)
+
+// ModuleResolver is called for each `import` of a module being instantiated.
+// It returns the Module for the given specifier, as imported by the referrer
+// module. The returned Module must have been compiled in the same Isolate.
+// A returned error is thrown as a JavaScript exception, failing the instantiation.
+type ModuleResolver func(ctx *Context, specifier string, referrer *Module) (*Module, error)
+
+// DynamicImportCallback is called for `import(specifier)` expressions in scripts
+// and modules; referrer is the origin of the calling script or module.
+// It starts loading the module and settles the import with imp.Resolve or
+// imp.Reject, either before returning or later from another goroutine, eg.
+// once the module has been read from storage, so that the Isolate is not
+// blocked while the module loads.
+type DynamicImportCallback func(ctx *Context, specifier, referrer string, imp *DynamicImport)
+
+// DynamicImport is a pending `import()` expression, which is settled with
+// Resolve or Reject. As with a PromiseResolver, these can be called from any
+// goroutine; the reactions of the Promise of the import() expression then
+// run at the next microtask checkpoint of the Context.
+type DynamicImport struct {
+	ctx       *Context
+	specifier string
+	resolver  *PromiseResolver
+}
+
+// ImportMetaCallback is called the first time `import.meta` is accessed in
+// a module, so that properties such as `url` can be set on the meta object.
+type ImportMetaCallback func(ctx *Context, module *Module, meta *Object)
+
+// Module is an ES module (ECMA-262, 16.2), compiled with Isolate.CompileModule.
+type Module struct {
+	ptr    C.ModulePtr
+	iso    *Isolate
+	origin string
+}
+
+// CompileModule compiles the source JavaScript as an ES module; origin
+// (a.k.a. filename) identifies the module, is used in the stack trace if there
+// is an error and is passed as the referrer to the ModuleResolver.
+// error will be of type `JSError` if not nil.
+func (i *Isolate) CompileModule(source, origin string) (*Module, error) {
+	i.recordCoverageSource(origin, source)
+	cSource := C.CString(source)
+	cOrigin := C.CString(origin)
+	defer C.free(unsafe.Pointer(cSource))
+	defer C.free(unsafe.Pointer(cOrigin))
+
+	rtn := C.IsolateCompileModule(i.ptr, cSource, cOrigin)
+	if rtn.ptr == nil {
+		return nil, newJSError(rtn.error)
+	}
+	m := &Module{
+		ptr:    rtn.ptr,
+		iso:    i,
+		origin: origin,
+	}
+	i.modMutex.Lock()
+	i.modules[m.ptr] = m
+	i.modMutex.Unlock()
+	return m, nil
+}
+
+// Origin returns the origin the module was compiled with.
+func (m *Module) Origin() string {
+	return m.origin
+}
+
+// Status returns the current status of the module.
+func (m *Module) Status() ModuleStatus {
+	return ModuleStatus(C.ModuleGetStatus(m.ptr))
+}
+
+// InstantiateModule instantiates the module and its dependencies in the given
+// context, using the resolver to look up the imported modules.
+// error will be of type `JSError` if not nil.
+func (m *Module) InstantiateModule(ctx *Context, resolver ModuleResolver) error {
+	if ctx.iso != m.iso {
+		panic("attempted to instantiate a module in a context that belongs to a different isolate")
+	}
+	prev := ctx.moduleResolver
+	ctx.moduleResolver = resolver
+	defer func() { ctx.moduleResolver = prev }()
+
+	rtn := C.ModuleInstantiate(m.ptr, ctx.ptr)
+	if rtn.value == 0 {
+		return newJSError(rtn.error)
+	}
+	return nil
+}
+
+// Evaluate runs the module and its dependencies. The module must have been
+// instantiated in the context. The returned Promise is fulfilled once the
+// module has been evaluated, which may be after a top-level await, or is
+// rejected with the exception thrown by the module.
+func (m *Module) Evaluate(ctx *Context) (*Promise, error) {
+	if ctx.iso != m.iso {
+		return nil, errors.New("v8go: context belongs to a different isolate than the module")
+	}
+	if m.Status() < ModuleInstantiated {
+		return nil, errors.New("v8go: module has not been instantiated")
+	}
+	rtn := C.ModuleEvaluate(m.ptr, ctx.ptr)
+	obj, err := objectResult(ctx, rtn)
+	if err != nil {
+		return nil, err
+	}
+	return &Promise{obj}, nil
+}
+
+// GetModuleNamespace returns the namespace object holding the exports of the
+// module. The module must have been instantiated in the context.
+func (m *Module) GetModuleNamespace(ctx *Context) (*Object, error) {
+	if m.Status() < ModuleInstantiated {
+		return nil, errors.New("v8go: module has not been instantiated")
+	}
+	ptr := C.ModuleGetNamespace(m.ptr, ctx.ptr)
+	return &Object{&Value{ptr, ctx}}, nil
+}
+
+// SetDynamicImportCallback sets the callback that loads the modules imported
+// with `import()`. Without a callback, dynamic imports are rejected.
+func (i *Isolate) SetDynamicImportCallback(cb DynamicImportCallback) {
+	i.cbMutex.Lock()
+	i.dynamicImportCb = cb
+	i.cbMutex.Unlock()
+	C.IsolateSetHostImportModuleDynamicallyCallback(i.ptr)
+}
+
+// SetImportMetaCallback sets the callback that populates `import.meta`.
+func (i *Isolate) SetImportMetaCallback(cb ImportMetaCallback) {
+	i.cbMutex.Lock()
+	i.importMetaCb = cb
+	i.cbMutex.Unlock()
+	C.IsolateSetHostInitializeImportMetaObjectCallback(i.ptr)
+}
+
+// Resolve settles the import with the Module, which is instantiated in the
+// Context of the import, with resolver resolving its static imports, unless
+// it is already, and evaluated. The Promise of the import() expression is
+// then fulfilled with the namespace object of the module, or rejected with
+// the exception thrown while instantiating or evaluating it.
+func (d *DynamicImport) Resolve(m *Module, resolver ModuleResolver) {
+	if m == nil {
+		d.Reject(fmt.Errorf("Cannot find module '%s'", d.specifier))
+		return
+	}
+	if m.iso != d.ctx.iso {
+		d.Reject(fmt.Errorf("Cannot import module '%s': module belongs to a different isolate", d.specifier))
+		return
+	}
+
+	if m.Status() == ModuleUninstantiated {
+		if err := m.InstantiateModule(d.ctx, resolver); err != nil {
+			d.Reject(err)
+			return
+		}
+	}
+	evaluated, err := m.Evaluate(d.ctx)
+	if err != nil {
+		d.Reject(err)
+		return
+	}
+	ns, err := m.GetModuleNamespace(d.ctx)
+	if err != nil {
+		d.Reject(err)
+		return
+	}
+	d.resolver.Resolve(evaluated.Then(func(*FunctionCallbackInfo) *Value {
+		return ns.Value
+	}))
+}
+
+// Reject rejects the Promise of the import() expression with err, which is
+// converted to a JS value as by NewFunctionTemplateWithError: a *JSError
+// rejects it with its thrown value.
+func (d *DynamicImport) Reject(err error) {
+	d.resolver.Reject(errorValue(d.ctx.iso, err))
+}
+
+// goDynamicImportCallback returns the Promise of an `import()` expression,
+// which the DynamicImportCallback settles.
+//
+//export goDynamicImportCallback
+func goDynamicImportCallback(ctxref int, specifier, referrer *C.char) C.ValuePtr {
+	ctx := getContext(ctxref)
+	resolver, _ := NewPromiseResolver(ctx)
+	imp := &DynamicImport{
+		ctx:       ctx,
+		specifier: C.GoString(specifier),
+		resolver:  resolver,
+	}
+
+	ctx.iso.cbMutex.RLock()
+	cb := ctx.iso.dynamicImportCb
+	ctx.iso.cbMutex.RUnlock()
+	if cb == nil {
+		imp.Reject(fmt.Errorf("Cannot import module '%s': no dynamic import callback", imp.specifier))
+	} else {
+		cb(ctx, imp.specifier, C.GoString(referrer), imp)
+	}
+	return resolver.GetPromise().ptr
+}
+
+//export goImportMetaCallback
+func goImportMetaCallback(ctxref int, module C.ModulePtr, meta C.ValuePtr) {
+	ctx := getContext(ctxref)
+	iso := ctx.iso
+
+	iso.cbMutex.RLock()
+	cb := iso.importMetaCb
+	iso.cbMutex.RUnlock()
+	if cb == nil {
+		return
+	}
+
+	iso.modMutex.RLock()
+	m := iso.modules[module]
+	iso.modMutex.RUnlock()
+
+	cb(ctx, m, &Object{&Value{meta, ctx}})
+}
+
+//export goResolveModuleCallback
+func goResolveModuleCallback(ctxref int, specifier *C.char, referrer C.ModulePtr) (C.ModulePtr, *C.char) {
+	ctx := getContext(ctxref)
+	spec := C.GoString(specifier)
+
+	if ctx.moduleResolver == nil {
+		return nil, C.CString(fmt.Sprintf("Cannot resolve module '%s': no module resolver", spec))
+	}
+
+	ctx.iso.modMutex.RLock()
+	ref := ctx.iso.modules[referrer]
+	ctx.iso.modMutex.RUnlock()
+
+	m, err := ctx.moduleResolver(ctx, spec, ref)
+	if err != nil {
+		return nil, C.CString(err.Error())
+	}
+	if m == nil {
+		return nil, C.CString(fmt.Sprintf("Cannot find module '%s'", spec))
+	}
+	if m.iso != ctx.iso {
+		return nil, C.CString(fmt.Sprintf("Cannot resolve module '%s': module belongs to a different isolate", spec))
+	}
+	return m.ptr, nil
+}
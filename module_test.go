@@ -0,0 +1,200 @@
+// Copyright 2023 the v8go contributors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package v8go_test
+
+import (
+	"errors"
+	"testing"
+
+	v8 "rogchap.com/v8go"
+)
+
+func TestModuleEvaluate(t *testing.T) {
+	t.Parallel()
+	iso := v8.NewIsolate()
+	defer iso.Dispose()
+	ctx := v8.NewContext(iso)
+	defer ctx.Close()
+
+	math, err := iso.CompileModule(`export function add(a, b) { return a + b; }`, "math.js")
+	fatalIf(t, err)
+	main, err := iso.CompileModule(`
+		import { add } from "./math.js";
+		export const sum = add(3, 4);
+		export const later = await Promise.resolve("done");
+	`, "main.js")
+	fatalIf(t, err)
+
+	if s := main.Status(); s != v8.ModuleUninstantiated {
+		t.Errorf("unexpected status: %v", s)
+	}
+
+	var referrer *v8.Module
+	err = main.InstantiateModule(ctx, func(ctx *v8.Context, specifier string, ref *v8.Module) (*v8.Module, error) {
+		referrer = ref
+		if specifier == "./math.js" {
+			return math, nil
+		}
+		return nil, errors.New("unknown module")
+	})
+	fatalIf(t, err)
+	if referrer != main {
+		t.Errorf("expected referrer to be the main module, got %v", referrer.Origin())
+	}
+
+	prom, err := main.Evaluate(ctx)
+	fatalIf(t, err)
+	ctx.PerformMicrotaskCheckpoint()
+	if prom.State() != v8.Fulfilled {
+		t.Fatalf("expected module evaluation to be fulfilled, got %v", prom.State())
+	}
+	if s := main.Status(); s != v8.ModuleEvaluated {
+		t.Errorf("unexpected status: %v", s)
+	}
+
+	ns, err := main.GetModuleNamespace(ctx)
+	fatalIf(t, err)
+	if !ns.IsModuleNamespaceObject() {
+		t.Error("expected a module namespace object")
+	}
+	sum, err := ns.Get("sum")
+	fatalIf(t, err)
+	if sum.Int32() != 7 {
+		t.Errorf("unexpected value for sum: %v", sum)
+	}
+	later, err := ns.Get("later")
+	fatalIf(t, err)
+	if later.String() != "done" {
+		t.Errorf("unexpected value for later: %v", later)
+	}
+}
+
+func TestModuleErrors(t *testing.T) {
+	t.Parallel()
+	iso := v8.NewIsolate()
+	defer iso.Dispose()
+	ctx := v8.NewContext(iso)
+	defer ctx.Close()
+
+	if _, err := iso.CompileModule(`export default {`, "invalid.js"); err == nil {
+		t.Error("expected compile error")
+	}
+
+	mod, err := iso.CompileModule(`import "missing";`, "main.js")
+	fatalIf(t, err)
+	if _, err := mod.GetModuleNamespace(ctx); err == nil {
+		t.Error("expected error getting namespace of uninstantiated module")
+	}
+	if _, err := mod.Evaluate(ctx); err == nil {
+		t.Error("expected error evaluating uninstantiated module")
+	}
+	iso2 := v8.NewIsolate()
+	defer iso2.Dispose()
+	ctx2 := v8.NewContext(iso2)
+	defer ctx2.Close()
+	if _, err := mod.Evaluate(ctx2); err == nil {
+		t.Error("expected error evaluating module in a context of another isolate")
+	}
+	err = mod.InstantiateModule(ctx, func(*v8.Context, string, *v8.Module) (*v8.Module, error) {
+		return nil, errors.New("not found")
+	})
+	var jsErr *v8.JSError
+	if !errors.As(err, &jsErr) || jsErr.Message != "Error: not found" {
+		t.Errorf("unexpected instantiate error: %v", err)
+	}
+
+	throws, err := iso.CompileModule(`throw new Error("oops");`, "throws.js")
+	fatalIf(t, err)
+	fatalIf(t, throws.InstantiateModule(ctx, nil))
+	prom, err := throws.Evaluate(ctx)
+	fatalIf(t, err)
+	if prom.State() != v8.Rejected {
+		t.Errorf("expected module evaluation to be rejected, got %v", prom.State())
+	}
+	if s := throws.Status(); s != v8.ModuleErrored {
+		t.Errorf("unexpected status: %v", s)
+	}
+}
+
+func TestModuleDynamicImport(t *testing.T) {
+	t.Parallel()
+	iso := v8.NewIsolate()
+	defer iso.Dispose()
+	ctx := v8.NewContext(iso)
+	defer ctx.Close()
+
+	sources := map[string]string{
+		"plugin.js": `import { name } from "name.js"; export default "hello " + name + " from " + import.meta.url;`,
+		"name.js":   `export const name = "plugin";`,
+		"broken.js": `throw new TypeError("broken");`,
+	}
+	resolve := func(ctx *v8.Context, specifier string, referrer *v8.Module) (*v8.Module, error) {
+		return ctx.Isolate().CompileModule(sources[specifier], specifier)
+	}
+	var referrers []string
+	loaded := make(chan struct{}, 1)
+	iso.SetDynamicImportCallback(func(ctx *v8.Context, specifier, referrer string, imp *v8.DynamicImport) {
+		referrers = append(referrers, referrer)
+		src, ok := sources[specifier]
+		if !ok {
+			imp.Reject(errors.New("no such plugin"))
+			return
+		}
+		// the module is loaded asynchronously, as from storage
+		go func() {
+			defer func() { loaded <- struct{}{} }()
+			m, err := ctx.Isolate().CompileModule(src, specifier)
+			if err != nil {
+				imp.Reject(err)
+				return
+			}
+			imp.Resolve(m, resolve)
+		}()
+	})
+	iso.SetImportMetaCallback(func(ctx *v8.Context, module *v8.Module, meta *v8.Object) {
+		meta.Set("url", "store://"+module.Origin())
+	})
+
+	val, err := ctx.RunScript(`import("plugin.js").then(m => m.default)`, "main.js")
+	fatalIf(t, err)
+	prom, err := val.AsPromise()
+	fatalIf(t, err)
+	if prom.State() != v8.Pending {
+		t.Errorf("expected import to be pending while the module loads, got %v", prom.State())
+	}
+	<-loaded
+	ctx.PerformMicrotaskCheckpoint()
+	if prom.State() != v8.Fulfilled {
+		t.Fatalf("expected import to be fulfilled, got %v: %v", prom.State(), prom.Result())
+	}
+	if s := prom.Result().String(); s != "hello plugin from store://plugin.js" {
+		t.Errorf("unexpected result: %q", s)
+	}
+	if len(referrers) != 1 || referrers[0] != "main.js" {
+		t.Errorf("unexpected referrers: %v", referrers)
+	}
+
+	val, err = ctx.RunScript(`import("missing.js")`, "main.js")
+	fatalIf(t, err)
+	prom, err = val.AsPromise()
+	fatalIf(t, err)
+	ctx.PerformMicrotaskCheckpoint()
+	if prom.State() != v8.Rejected {
+		t.Fatalf("expected import to be rejected, got %v", prom.State())
+	}
+	if s := prom.Result().String(); s != "Error: no such plugin" {
+		t.Errorf("unexpected rejection: %q", s)
+	}
+
+	val, err = ctx.RunScript(`import("broken.js").catch(e => e instanceof TypeError)`, "main.js")
+	fatalIf(t, err)
+	prom, err = val.AsPromise()
+	fatalIf(t, err)
+	<-loaded
+	ctx.PerformMicrotaskCheckpoint()
+	if !prom.Result().Boolean() {
+		t.Errorf("expected import to be rejected with a TypeError, got %v", prom.Result())
+	}
+}
@@ -156,3 +156,42 @@ func TestObjectTemplate_garbageCollection(t *testing.T) {
 
 	runtime.GC()
 }
+
+func TestObjectTemplateSetAccessor(t *testing.T) {
+	t.Parallel()
+	iso := v8.NewIsolate()
+	defer iso.Dispose()
+
+	type config struct{ name string }
+	cfg := &config{name: "initial"}
+
+	tmpl := v8.NewObjectTemplate(iso)
+	tmpl.SetAccessor("name", func(info *v8.PropertyCallbackInfo) *v8.Value {
+		val, _ := v8.NewValue(iso, cfg.name)
+		return val
+	}, func(info *v8.PropertyCallbackInfo) {
+		cfg.name = info.Value().String()
+	})
+	tmpl.SetAccessor("version", func(info *v8.PropertyCallbackInfo) *v8.Value {
+		if !info.This().Has("name") {
+			t.Error("expected receiver to have the name accessor")
+		}
+		val, _ := v8.NewValue(iso, int32(2))
+		return val
+	}, nil, v8.DontEnum)
+
+	ctx := v8.NewContext(iso)
+	defer ctx.Close()
+	obj, err := tmpl.NewInstance(ctx)
+	fatalIf(t, err)
+	fatalIf(t, ctx.Global().Set("config", obj))
+
+	val, err := ctx.RunScript(`config.name = "updated"; config.version = 3; [config.name, config.version, Object.keys(config).join()].join(" ")`, "")
+	fatalIf(t, err)
+	if s := val.String(); s != "updated 2 name" {
+		t.Errorf("unexpected value: %q", s)
+	}
+	if cfg.name != "updated" {
+		t.Errorf("expected setter to update the Go value, got %q", cfg.name)
+	}
+}
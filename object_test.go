@@ -202,6 +202,40 @@ func TestObjectDelete(t *testing.T) {
 
 }
 
+func TestObjectSetAccessor(t *testing.T) {
+	t.Parallel()
+	iso := v8.NewIsolate()
+	defer iso.Dispose()
+	ctx := v8.NewContext(iso)
+	defer ctx.Close()
+
+	var count int32
+	global := ctx.Global()
+	err := global.SetAccessor("count", func(info *v8.PropertyCallbackInfo) *v8.Value {
+		if info.Context() != ctx {
+			t.Error("unexpected context")
+		}
+		val, _ := v8.NewValue(iso, count)
+		return val
+	}, func(info *v8.PropertyCallbackInfo) {
+		count = info.Value().Int32()
+	}, v8.DontDelete)
+	fatalIf(t, err)
+
+	val, err := ctx.RunScript(`count = 41; delete count; ++count`, "")
+	fatalIf(t, err)
+	if val.Int32() != 42 || count != 42 {
+		t.Errorf("unexpected values: %v, %d", val, count)
+	}
+
+	frozen, err := ctx.RunScript(`Object.freeze({})`, "")
+	fatalIf(t, err)
+	obj, _ := frozen.AsObject()
+	if err := obj.SetAccessor("foo", func(*v8.PropertyCallbackInfo) *v8.Value { return nil }, nil); err == nil {
+		t.Error("expected error defining an accessor on a frozen object")
+	}
+}
+
 func ExampleObject_global() {
 	iso := v8.NewIsolate()
 	defer iso.Dispose()
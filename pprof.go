@@ -0,0 +1,243 @@
+// Copyright 2023 the v8go contributors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package v8go
+
+import (
+	"compress/gzip"
+	"io"
+	"sort"
+	"time"
+)
+
+// profileBuilder encodes a profile in the gzipped protocol buffer format of
+// pprof, see https://github.com/google/pprof/blob/main/proto/profile.proto.
+type profileBuilder struct {
+	// pb holds the encoded fields of the Profile message, except the string
+	// table which is written last.
+	pb          protobuf
+	strings     map[string]int64
+	stringTable []string
+	functions   map[pprofFunction]uint64
+	locations   map[pprofLocation]uint64
+}
+
+// pprofFunction is a JavaScript function; its line is where it starts.
+type pprofFunction struct {
+	name     string
+	filename string
+	line     int64
+}
+
+type pprofLocation struct {
+	function uint64
+	line     int64
+	column   int64
+}
+
+// Field numbers of the messages of profile.proto.
+const (
+	profileSampleType    = 1
+	profileSample        = 2
+	profileLocation      = 4
+	profileFunction      = 5
+	profileStringTable   = 6
+	profileTimeNanos     = 9
+	profileDurationNanos = 10
+	profilePeriodType    = 11
+	profilePeriod        = 12
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocationID = 1
+	sampleValue      = 2
+	sampleLabel      = 3
+
+	labelKey = 1
+	labelStr = 2
+	labelNum = 3
+
+	locationID   = 1
+	locationLine = 4
+
+	lineFunctionID = 1
+	lineLine       = 2
+	lineColumn     = 3
+
+	functionID        = 1
+	functionName      = 2
+	functionFilename  = 4
+	functionStartLine = 5
+)
+
+func newProfileBuilder() *profileBuilder {
+	return &profileBuilder{
+		strings:     map[string]int64{"": 0},
+		stringTable: []string{""},
+		functions:   make(map[pprofFunction]uint64),
+		locations:   make(map[pprofLocation]uint64),
+	}
+}
+
+// str returns the index of s in the string table.
+func (b *profileBuilder) str(s string) int64 {
+	if i, ok := b.strings[s]; ok {
+		return i
+	}
+	i := int64(len(b.stringTable))
+	b.strings[s] = i
+	b.stringTable = append(b.stringTable, s)
+	return i
+}
+
+func (b *profileBuilder) valueType(field int, typ, unit string) {
+	var vt protobuf
+	vt.int64(valueTypeType, b.str(typ))
+	vt.int64(valueTypeUnit, b.str(unit))
+	b.pb.message(field, vt)
+}
+
+// sampleTypes sets the types of the values of the samples.
+func (b *profileBuilder) sampleTypes(types ...[2]string) {
+	for _, t := range types {
+		b.valueType(profileSampleType, t[0], t[1])
+	}
+}
+
+func (b *profileBuilder) period(typ, unit string, period int64) {
+	b.valueType(profilePeriodType, typ, unit)
+	b.pb.int64(profilePeriod, period)
+}
+
+func (b *profileBuilder) timing(start time.Time, duration time.Duration) {
+	if !start.IsZero() {
+		b.pb.int64(profileTimeNanos, start.UnixNano())
+	}
+	b.pb.int64(profileDurationNanos, int64(duration))
+}
+
+// location returns the id of the location of a line in a function, adding
+// the location and function to the profile if needed.
+func (b *profileBuilder) location(fn pprofFunction, line, column int64) uint64 {
+	fnID, ok := b.functions[fn]
+	if !ok {
+		fnID = uint64(len(b.functions) + 1)
+		b.functions[fn] = fnID
+		var f protobuf
+		f.uint64(functionID, fnID)
+		f.int64(functionName, b.str(fn.name))
+		f.int64(functionFilename, b.str(fn.filename))
+		f.int64(functionStartLine, fn.line)
+		b.pb.message(profileFunction, f)
+	}
+
+	key := pprofLocation{fnID, line, column}
+	locID, ok := b.locations[key]
+	if !ok {
+		locID = uint64(len(b.locations) + 1)
+		b.locations[key] = locID
+		var ln protobuf
+		ln.uint64(lineFunctionID, fnID)
+		ln.int64(lineLine, line)
+		ln.int64(lineColumn, column)
+		var loc protobuf
+		loc.uint64(locationID, locID)
+		loc.message(locationLine, ln)
+		b.pb.message(profileLocation, loc)
+	}
+	return locID
+}
+
+// sample adds a sample with the stack of locations, the innermost first.
+func (b *profileBuilder) sample(locations []uint64, values []int64, labels map[string]int64) {
+	var s protobuf
+	s.packedUint64s(sampleLocationID, locations)
+	s.packedInt64s(sampleValue, values)
+	// the keys are sorted so that the output is deterministic
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var l protobuf
+		l.int64(labelKey, b.str(k))
+		l.int64(labelNum, labels[k])
+		s.message(sampleLabel, l)
+	}
+	b.pb.message(profileSample, s)
+}
+
+func (b *profileBuilder) write(w io.Writer) error {
+	for _, s := range b.stringTable {
+		b.pb.string(profileStringTable, s)
+	}
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(b.pb); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// protobuf is an encoded protocol buffer message.
+type protobuf []byte
+
+func (pb *protobuf) varint(v uint64) {
+	for v >= 0x80 {
+		*pb = append(*pb, byte(v)|0x80)
+		v >>= 7
+	}
+	*pb = append(*pb, byte(v))
+}
+
+func (pb *protobuf) key(field int, wireType int) {
+	pb.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+func (pb *protobuf) uint64(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	pb.key(field, 0)
+	pb.varint(v)
+}
+
+func (pb *protobuf) int64(field int, v int64) {
+	pb.uint64(field, uint64(v))
+}
+
+func (pb *protobuf) string(field int, s string) {
+	pb.key(field, 2)
+	pb.varint(uint64(len(s)))
+	*pb = append(*pb, s...)
+}
+
+func (pb *protobuf) message(field int, msg protobuf) {
+	pb.key(field, 2)
+	pb.varint(uint64(len(msg)))
+	*pb = append(*pb, msg...)
+}
+
+func (pb *protobuf) packedUint64s(field int, vs []uint64) {
+	if len(vs) == 0 {
+		return
+	}
+	var packed protobuf
+	for _, v := range vs {
+		packed.varint(v)
+	}
+	pb.message(field, packed)
+}
+
+func (pb *protobuf) packedInt64s(field int, vs []int64) {
+	if len(vs) == 0 {
+		return
+	}
+	var packed protobuf
+	for _, v := range vs {
+		packed.varint(uint64(v))
+	}
+	pb.message(field, packed)
+}
@@ -0,0 +1,180 @@
+// Copyright 2023 the v8go contributors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package v8go
+
+// #include "v8go.h"
+import "C"
+import (
+	"errors"
+	"unsafe"
+)
+
+// FunctionCodeHandling determines whether the compiled code of functions is
+// kept in the snapshot created by a SnapshotCreator.
+type FunctionCodeHandling int
+
+const (
+	// FunctionCodeHandlingClear discards compiled code; functions are lazily
+	// recompiled when first called after deserialization.
+	FunctionCodeHandlingClear FunctionCodeHandling = iota
+	// FunctionCodeHandlingKeep keeps compiled code in the snapshot.
+	FunctionCodeHandlingKeep
+)
+
+// StartupData is a serialized snapshot of an Isolate heap, as created by
+// SnapshotCreator.Create. It can be stored and passed to NewIsolate with the
+// WithStartupData option to create pre-initialized isolates.
+// The snapshot is only valid for the V8 version and flags that created it.
+type StartupData struct {
+	Bytes []byte
+}
+
+// WithStartupData creates the new Isolate from the snapshot in data.
+// The callbacks of the FunctionTemplates in the snapshot are referenced by
+// the order in which they were created; callbacks must be given in that same
+// order for the functions in the snapshot to call them.
+func WithStartupData(data *StartupData, callbacks ...FunctionCallback) IsolateOption {
+	return func(opts *isolateOptions) {
+		opts.startupData = data
+		opts.callbacks = callbacks
+	}
+}
+
+// WithStartupAccessors gives the callbacks of the accessors in the snapshot
+// of WithStartupData, which are referenced by the order in which they were
+// set with ObjectTemplate.SetAccessor and Object.SetAccessor. Accessors that
+// are not given read as undefined and ignore assignments.
+func WithStartupAccessors(accessors ...Accessor) IsolateOption {
+	return func(opts *isolateOptions) {
+		opts.accessors = accessors
+	}
+}
+
+// WithStartupPropertyHandlers gives the callbacks of the property handlers in
+// the snapshot of WithStartupData, which are referenced by the order in which
+// they were set with ObjectTemplate.SetNamedPropertyHandler and
+// ObjectTemplate.SetIndexedPropertyHandler. Handlers that are not given do
+// not intercept any request.
+func WithStartupPropertyHandlers(handlers ...PropertyHandler) IsolateOption {
+	return func(opts *isolateOptions) {
+		opts.handlers = handlers
+	}
+}
+
+// FromSnapshot creates the new Context from the context that was added to
+// the snapshot of the Isolate at index, as returned by SnapshotCreator.AddContext.
+// The Isolate must have been created with WithStartupData.
+func FromSnapshot(index int) ContextOption {
+	return fromSnapshot(index)
+}
+
+type fromSnapshot int
+
+func (f fromSnapshot) apply(opts *contextOptions) {
+	opts.fromSnapshot = true
+	opts.snapshotIndex = int(f)
+}
+
+// SnapshotCreator creates a snapshot of the heap of its Isolate, which
+// contains the contexts added to it along with everything reachable from them.
+// Scripts run in these contexts before calling Create are not run again in
+// an Isolate created from the snapshot.
+type SnapshotCreator struct {
+	ptr C.SnapshotCreatorPtr
+	iso *Isolate
+	// ctxs are all the contexts created in the Isolate, which are closed
+	// before it is disposed.
+	ctxs []*Context
+}
+
+// NewSnapshotCreator creates a SnapshotCreator with a new Isolate.
+// Either Create or Dispose must be called to free its resources.
+func NewSnapshotCreator() *SnapshotCreator {
+	initializeIfNecessary()
+	iso := newIsolate()
+	sc := &SnapshotCreator{
+		ptr: C.NewSnapshotCreator(C.int(iso.ref)),
+		iso: iso,
+	}
+	iso.ptr = C.SnapshotCreatorGetIsolate(sc.ptr)
+	iso.snapshotCreator = sc
+	iso.init()
+	return sc
+}
+
+// Isolate returns the Isolate whose heap is serialized by the SnapshotCreator.
+// It must not be disposed directly.
+func (s *SnapshotCreator) Isolate() *Isolate {
+	return s.iso
+}
+
+// SetDefaultContext sets the context that is used by NewContext for an
+// Isolate created from the snapshot. Without a default context, a new empty
+// context is used.
+func (s *SnapshotCreator) SetDefaultContext(ctx *Context) {
+	s.checkContext(ctx)
+	C.SnapshotCreatorSetDefaultContext(s.ptr, ctx.ptr)
+}
+
+// AddContext adds an additional context to the snapshot, returning the index
+// to create it from with the FromSnapshot option.
+func (s *SnapshotCreator) AddContext(ctx *Context) int {
+	s.checkContext(ctx)
+	idx := C.SnapshotCreatorAddContext(s.ptr, ctx.ptr)
+	return int(idx)
+}
+
+func (s *SnapshotCreator) checkContext(ctx *Context) {
+	if s.ptr == nil {
+		panic("v8go: SnapshotCreator has been disposed")
+	}
+	if ctx.iso != s.iso {
+		panic("v8go: context does not belong to the Isolate of the SnapshotCreator")
+	}
+}
+
+// Create serializes the heap of the Isolate into StartupData. All the
+// contexts of the Isolate are closed, and the SnapshotCreator and its Isolate
+// are disposed; neither can be used afterwards.
+func (s *SnapshotCreator) Create(handling FunctionCodeHandling) (*StartupData, error) {
+	if s.ptr == nil {
+		panic("v8go: SnapshotCreator has been disposed")
+	}
+	s.closeContexts()
+	blob := C.SnapshotCreatorCreateBlob(s.ptr, C.int(handling))
+	s.dispose()
+
+	if blob.data == nil {
+		return nil, errors.New("v8go: failed to create snapshot")
+	}
+	defer C.SnapshotBlobDelete(blob)
+	return &StartupData{Bytes: C.GoBytes(unsafe.Pointer(blob.data), blob.raw_size)}, nil
+}
+
+// Dispose frees the SnapshotCreator and its Isolate without creating a
+// snapshot. It is a no-op after Create.
+func (s *SnapshotCreator) Dispose() {
+	if s.ptr == nil {
+		return
+	}
+	s.closeContexts()
+	C.SnapshotCreatorDispose(s.ptr)
+	s.dispose()
+}
+
+func (s *SnapshotCreator) closeContexts() {
+	for _, ctx := range s.ctxs {
+		if ctx.ptr != nil {
+			ctx.Close()
+		}
+	}
+	s.ctxs = nil
+}
+
+func (s *SnapshotCreator) dispose() {
+	s.ptr = nil
+	s.iso.ptr = nil
+	s.iso.deregister()
+}
@@ -0,0 +1,208 @@
+// Copyright 2023 the v8go contributors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package v8go_test
+
+import (
+	"testing"
+
+	v8 "rogchap.com/v8go"
+)
+
+func TestSnapshotCreator(t *testing.T) {
+	t.Parallel()
+
+	greet := func(info *v8.FunctionCallbackInfo) *v8.Value {
+		val, _ := v8.NewValue(info.Context().Isolate(), "hello "+info.Args()[0].String())
+		return val
+	}
+
+	sc := v8.NewSnapshotCreator()
+	iso := sc.Isolate()
+	global := v8.NewObjectTemplate(iso)
+	fatalIf(t, global.Set("greet", v8.NewFunctionTemplate(iso, greet)))
+
+	ctx := v8.NewContext(iso, global)
+	_, err := ctx.RunScript(`const counter = { n: 41 }; function next() { return ++counter.n; }`, "setup.js")
+	fatalIf(t, err)
+	sc.SetDefaultContext(ctx)
+
+	other := v8.NewContext(iso)
+	_, err = other.RunScript(`var name = "other";`, "other.js")
+	fatalIf(t, err)
+	idx := sc.AddContext(other)
+
+	data, err := sc.Create(v8.FunctionCodeHandlingKeep)
+	fatalIf(t, err)
+	if len(data.Bytes) == 0 {
+		t.Fatal("expected snapshot data")
+	}
+	sc.Dispose() // no-op after Create
+
+	iso2 := v8.NewIsolate(v8.WithStartupData(data, greet))
+	defer iso2.Dispose()
+
+	ctx2 := v8.NewContext(iso2)
+	defer ctx2.Close()
+	val, err := ctx2.RunScript(`next()`, "main.js")
+	fatalIf(t, err)
+	if val.Int32() != 42 {
+		t.Errorf("unexpected value: %v", val)
+	}
+	val, err = ctx2.RunScript(`greet("snapshot")`, "main.js")
+	fatalIf(t, err)
+	if val.String() != "hello snapshot" {
+		t.Errorf("unexpected value: %v", val)
+	}
+
+	ctx3 := v8.NewContext(iso2, v8.FromSnapshot(idx))
+	defer ctx3.Close()
+	val, err = ctx3.RunScript(`name`, "main.js")
+	fatalIf(t, err)
+	if val.String() != "other" {
+		t.Errorf("unexpected value: %v", val)
+	}
+}
+
+func TestSnapshotCreatorDispose(t *testing.T) {
+	t.Parallel()
+
+	sc := v8.NewSnapshotCreator()
+	ctx := v8.NewContext(sc.Isolate())
+	sc.AddContext(ctx)
+	sc.Dispose()
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic creating a snapshot after Dispose")
+		}
+	}()
+	sc.Create(v8.FunctionCodeHandlingClear)
+}
+
+func TestSnapshotCreatorClosesContexts(t *testing.T) {
+	t.Parallel()
+
+	sc := v8.NewSnapshotCreator()
+	iso := sc.Isolate()
+	ctx := v8.NewContext(iso)
+	_, err := ctx.RunScript(`var x = 1`, "default.js")
+	fatalIf(t, err)
+	sc.SetDefaultContext(ctx)
+	// a context that is not added to the snapshot
+	other := v8.NewContext(iso)
+	_, err = other.RunScript(`var y = 2`, "other.js")
+	fatalIf(t, err)
+
+	_, err = sc.Create(v8.FunctionCodeHandlingClear)
+	fatalIf(t, err)
+	// the contexts have been closed with the Isolate
+	other.Close()
+	ctx.Close()
+}
+
+func TestSnapshotCreatorAccessors(t *testing.T) {
+	t.Parallel()
+
+	greet := func(info *v8.FunctionCallbackInfo) *v8.Value {
+		val, _ := v8.NewValue(info.Context().Isolate(), "hello")
+		return val
+	}
+	var stored int32 = 41
+	answer := v8.Accessor{
+		Getter: func(info *v8.PropertyCallbackInfo) *v8.Value {
+			val, _ := v8.NewValue(info.Context().Isolate(), stored)
+			return val
+		},
+		Setter: func(info *v8.PropertyCallbackInfo) {
+			stored = info.Value().Int32()
+		},
+	}
+
+	sc := v8.NewSnapshotCreator()
+	iso := sc.Isolate()
+	global := v8.NewObjectTemplate(iso)
+	fatalIf(t, global.Set("greet", v8.NewFunctionTemplate(iso, greet)))
+	global.SetAccessor("answer", answer.Getter, answer.Setter)
+	ctx := v8.NewContext(iso, global)
+	sc.SetDefaultContext(ctx)
+	data, err := sc.Create(v8.FunctionCodeHandlingClear)
+	fatalIf(t, err)
+
+	iso2 := v8.NewIsolate(v8.WithStartupData(data, greet), v8.WithStartupAccessors(answer))
+	defer iso2.Dispose()
+	ctx2 := v8.NewContext(iso2)
+	defer ctx2.Close()
+	val, err := ctx2.RunScript(`answer++; greet() + " " + answer`, "main.js")
+	fatalIf(t, err)
+	if s := val.String(); s != "hello 42" {
+		t.Errorf("unexpected value: %q", s)
+	}
+
+	iso3 := v8.NewIsolate(v8.WithStartupData(data, greet))
+	defer iso3.Dispose()
+	ctx3 := v8.NewContext(iso3)
+	defer ctx3.Close()
+	val, err = ctx3.RunScript(`answer = 1; answer`, "main.js")
+	fatalIf(t, err)
+	if !val.IsUndefined() {
+		t.Errorf("expected an accessor that was not restored to be undefined, got %v", val)
+	}
+}
+
+func TestSnapshotCreatorPropertyHandlers(t *testing.T) {
+	t.Parallel()
+
+	env := map[string]string{"HOME": "/root"}
+	named := v8.NamedPropertyHandler{
+		Getter: func(info *v8.PropertyCallbackInfo) *v8.Value {
+			s, ok := env[info.Key()]
+			if !ok {
+				return nil
+			}
+			val, _ := v8.NewValue(info.Context().Isolate(), s)
+			return val
+		},
+	}
+	indexed := v8.IndexedPropertyHandler{
+		Getter: func(info *v8.PropertyCallbackInfo) *v8.Value {
+			val, _ := v8.NewValue(info.Context().Isolate(), int32(info.Index()*2))
+			return val
+		},
+	}
+
+	sc := v8.NewSnapshotCreator()
+	iso := sc.Isolate()
+	env1 := v8.NewObjectTemplate(iso)
+	env1.SetNamedPropertyHandler(named)
+	doubles := v8.NewObjectTemplate(iso)
+	doubles.SetIndexedPropertyHandler(indexed)
+	global := v8.NewObjectTemplate(iso)
+	fatalIf(t, global.Set("env", env1))
+	fatalIf(t, global.Set("doubles", doubles))
+	ctx := v8.NewContext(iso, global)
+	sc.SetDefaultContext(ctx)
+	data, err := sc.Create(v8.FunctionCodeHandlingClear)
+	fatalIf(t, err)
+
+	iso2 := v8.NewIsolate(v8.WithStartupData(data), v8.WithStartupPropertyHandlers(named, indexed))
+	defer iso2.Dispose()
+	ctx2 := v8.NewContext(iso2)
+	defer ctx2.Close()
+	val, err := ctx2.RunScript(`env.HOME + " " + doubles[21]`, "main.js")
+	fatalIf(t, err)
+	if s := val.String(); s != "/root 42" {
+		t.Errorf("unexpected value: %q", s)
+	}
+
+	iso3 := v8.NewIsolate(v8.WithStartupData(data))
+	defer iso3.Dispose()
+	ctx3 := v8.NewContext(iso3)
+	defer ctx3.Close()
+	val, err = ctx3.RunScript(`env.HOME`, "main.js")
+	fatalIf(t, err)
+	if !val.IsUndefined() {
+		t.Errorf("expected a handler that was not restored not to intercept, got %v", val)
+	}
+}
@@ -0,0 +1,291 @@
+// Copyright 2023 the v8go contributors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package v8go
+
+// #include <stdlib.h>
+// #include "v8go.h"
+import "C"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SourceMap is a parsed source map (revision 3), which maps positions in a
+// generated script, such as a bundle or the output of a compiler, back to
+// positions in its original sources. See Isolate.SetSourceMap.
+type SourceMap struct {
+	sources []string
+	names   []string
+	// lines holds the mappings of each generated line, sorted by column.
+	lines [][]mapping
+}
+
+type mapping struct {
+	genColumn int
+	source    int // -1 if the segment has no source position
+	line      int
+	column    int
+	name      int // -1 if the segment has no name
+}
+
+// SourcePosition is a position in an original source, as mapped by a SourceMap.
+type SourcePosition struct {
+	Source string
+	// Line and Column are 1-based.
+	Line   int
+	Column int
+	// Name is the original name of the symbol at the position, if known.
+	Name string
+}
+
+type sourceMapJSON struct {
+	Version    int               `json:"version"`
+	SourceRoot string            `json:"sourceRoot"`
+	Sources    []string          `json:"sources"`
+	Names      []string          `json:"names"`
+	Mappings   string            `json:"mappings"`
+	Sections   []json.RawMessage `json:"sections"`
+}
+
+// ParseSourceMap parses a source map in the revision 3 JSON format.
+// Index maps, with sections, are not supported.
+func ParseSourceMap(data []byte) (*SourceMap, error) {
+	var raw sourceMapJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("v8go: invalid source map: %w", err)
+	}
+	if raw.Version != 3 {
+		return nil, fmt.Errorf("v8go: unsupported source map version %d", raw.Version)
+	}
+	if raw.Sections != nil {
+		return nil, errors.New("v8go: source maps with sections are not supported")
+	}
+
+	sm := &SourceMap{
+		sources: make([]string, len(raw.Sources)),
+		names:   raw.Names,
+	}
+	for i, s := range raw.Sources {
+		if raw.SourceRoot != "" && !strings.Contains(s, "://") && !strings.HasPrefix(s, "/") {
+			s = strings.TrimSuffix(raw.SourceRoot, "/") + "/" + s
+		}
+		sm.sources[i] = s
+	}
+	if err := sm.parseMappings(raw.Mappings); err != nil {
+		return nil, err
+	}
+	return sm, nil
+}
+
+// parseMappings decodes the Base64 VLQ segments of the mappings; all fields
+// but the generated column are relative to the previous segment of any line.
+func (sm *SourceMap) parseMappings(mappings string) error {
+	var source, line, column, name int
+	for _, l := range strings.Split(mappings, ";") {
+		var segments []mapping
+		genColumn := 0
+		for _, seg := range strings.Split(l, ",") {
+			if seg == "" {
+				continue
+			}
+			fields, err := decodeVLQ(seg)
+			if err != nil {
+				return err
+			}
+			m := mapping{source: -1, name: -1}
+			switch len(fields) {
+			case 5:
+				name += fields[4]
+				m.name = name
+				fallthrough
+			case 4:
+				source += fields[1]
+				line += fields[2]
+				column += fields[3]
+				m.source, m.line, m.column = source, line, column
+				fallthrough
+			case 1:
+				genColumn += fields[0]
+				m.genColumn = genColumn
+			default:
+				return fmt.Errorf("v8go: invalid source map segment %q", seg)
+			}
+			if m.source >= len(sm.sources) || m.name >= len(sm.names) {
+				return fmt.Errorf("v8go: invalid source map segment %q", seg)
+			}
+			segments = append(segments, m)
+		}
+		sort.SliceStable(segments, func(i, j int) bool {
+			return segments[i].genColumn < segments[j].genColumn
+		})
+		sm.lines = append(sm.lines, segments)
+	}
+	return nil
+}
+
+const base64Chars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+func decodeVLQ(seg string) ([]int, error) {
+	var fields []int
+	var value, shift int
+	for i := 0; i < len(seg); i++ {
+		digit := strings.IndexByte(base64Chars, seg[i])
+		if digit < 0 {
+			return nil, fmt.Errorf("v8go: invalid source map segment %q", seg)
+		}
+		value += (digit & 31) << shift
+		if digit&32 != 0 {
+			shift += 5
+			continue
+		}
+		if value&1 != 0 {
+			fields = append(fields, -(value >> 1))
+		} else {
+			fields = append(fields, value>>1)
+		}
+		value, shift = 0, 0
+	}
+	if shift != 0 {
+		return nil, fmt.Errorf("v8go: invalid source map segment %q", seg)
+	}
+	return fields, nil
+}
+
+// Lookup returns the original position of the 1-based line and column in the
+// generated script; ok is false if the position is not mapped.
+func (sm *SourceMap) Lookup(line, column int) (pos SourcePosition, ok bool) {
+	if line < 1 || line > len(sm.lines) {
+		return pos, false
+	}
+	segments := sm.lines[line-1]
+	// the mapping of a position is the last segment starting at or before it
+	i := sort.Search(len(segments), func(i int) bool {
+		return segments[i].genColumn > column-1
+	}) - 1
+	if i < 0 || segments[i].source < 0 {
+		return pos, false
+	}
+	m := segments[i]
+	pos = SourcePosition{
+		Source: sm.sources[m.source],
+		Line:   m.line + 1,
+		Column: m.column + 1,
+	}
+	if m.name >= 0 {
+		pos.Name = sm.names[m.name]
+	}
+	return pos, true
+}
+
+// SetSourceMap registers the source map of the script with the given origin,
+// or removes it if sm is nil. The script positions of errors are mapped to
+// their original sources: the Location and Frames of a JSError, the Frame of
+// a ConsoleMessage, and the `stack` of errors in JavaScript, unless scripts
+// set Error.prepareStackTrace.
+func (i *Isolate) SetSourceMap(origin string, sm *SourceMap) {
+	i.smMutex.Lock()
+	defer i.smMutex.Unlock()
+	if sm == nil {
+		delete(i.sourceMaps, origin)
+	} else {
+		if i.sourceMaps == nil {
+			i.sourceMaps = make(map[string]*SourceMap)
+			C.IsolateSetPrepareStackTraceCallback(i.ptr)
+		}
+		i.sourceMaps[origin] = sm
+	}
+	i.smLocations = locationsRegexp(i.sourceMaps)
+}
+
+// locationsRegexp returns the regexp matching the `origin:line:column`
+// locations of the scripts with a source map, or nil if there are none.
+// An origin must start the string or follow a space, "(" or "@", so that it
+// does not match the end of another origin.
+func locationsRegexp(sourceMaps map[string]*SourceMap) *regexp.Regexp {
+	if len(sourceMaps) == 0 {
+		return nil
+	}
+	origins := make([]string, 0, len(sourceMaps))
+	for o := range sourceMaps {
+		origins = append(origins, regexp.QuoteMeta(o))
+	}
+	return regexp.MustCompile(`(^|[\s(@])(` + strings.Join(origins, "|") + `):(\d+):(\d+)`)
+}
+
+// mapPosition returns the original position of a position in a script.
+func (i *Isolate) mapPosition(origin string, line, column int) (SourcePosition, bool) {
+	i.smMutex.RLock()
+	sm := i.sourceMaps[origin]
+	i.smMutex.RUnlock()
+	if sm == nil {
+		return SourcePosition{}, false
+	}
+	return sm.Lookup(line, column)
+}
+
+// mapLocations replaces the `origin:line:column` locations in s, such as in a
+// stack trace, of the scripts with a source map.
+func (i *Isolate) mapLocations(s string) string {
+	i.smMutex.RLock()
+	re := i.smLocations
+	i.smMutex.RUnlock()
+	if re == nil {
+		return s
+	}
+
+	return re.ReplaceAllStringFunc(s, func(loc string) string {
+		m := re.FindStringSubmatch(loc)
+		line, _ := strconv.Atoi(m[3])
+		column, _ := strconv.Atoi(m[4])
+		pos, ok := i.mapPosition(m[2], line, column)
+		if !ok {
+			return loc
+		}
+		return fmt.Sprintf("%s%s:%d:%d", m[1], pos.Source, pos.Line, pos.Column)
+	})
+}
+
+// applySourceMaps maps the Location and Frames of the error.
+func (i *Isolate) applySourceMaps(e *JSError) {
+	i.smMutex.RLock()
+	n := len(i.sourceMaps)
+	i.smMutex.RUnlock()
+	if n == 0 {
+		return
+	}
+
+	e.Location = i.mapLocations(e.Location)
+	for idx := range e.Frames {
+		i.mapStackFrame(&e.Frames[idx])
+	}
+}
+
+// mapStackFrame maps the position of the frame, if its script has a source map.
+func (i *Isolate) mapStackFrame(f *StackFrame) {
+	if pos, ok := i.mapPosition(f.ScriptName, f.Line, f.Column); ok {
+		f.ScriptName = pos.Source
+		f.Line = pos.Line
+		f.Column = pos.Column
+	}
+}
+
+//export goMapStackTrace
+func goMapStackTrace(isoref int, stack *C.char) *C.char {
+	iso := getIsolate(isoref)
+	if iso == nil {
+		return nil
+	}
+	s := C.GoString(stack)
+	mapped := iso.mapLocations(s)
+	if mapped == s {
+		return nil
+	}
+	return C.CString(mapped)
+}
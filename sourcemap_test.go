@@ -0,0 +1,115 @@
+// Copyright 2023 the v8go contributors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package v8go_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	v8 "rogchap.com/v8go"
+)
+
+func TestParseSourceMap(t *testing.T) {
+	t.Parallel()
+
+	sm, err := v8.ParseSourceMap([]byte(`{
+		"version": 3,
+		"sourceRoot": "src",
+		"sources": ["a.ts", "/abs/b.ts"],
+		"names": ["foo"],
+		"mappings": "AAAAA,IAAI;AACA,ICDAA"
+	}`))
+	fatalIf(t, err)
+
+	tests := [...]struct {
+		line, column int
+		expected     v8.SourcePosition
+		ok           bool
+	}{
+		{1, 1, v8.SourcePosition{Source: "src/a.ts", Line: 1, Column: 1, Name: "foo"}, true},
+		{1, 3, v8.SourcePosition{Source: "src/a.ts", Line: 1, Column: 1, Name: "foo"}, true},
+		{1, 5, v8.SourcePosition{Source: "src/a.ts", Line: 1, Column: 5}, true},
+		{2, 1, v8.SourcePosition{Source: "src/a.ts", Line: 2, Column: 5}, true},
+		{2, 9, v8.SourcePosition{Source: "/abs/b.ts", Line: 1, Column: 5, Name: "foo"}, true},
+		{3, 1, v8.SourcePosition{}, false},
+		{0, 1, v8.SourcePosition{}, false},
+	}
+	for _, tt := range tests {
+		pos, ok := sm.Lookup(tt.line, tt.column)
+		if ok != tt.ok || pos != tt.expected {
+			t.Errorf("unexpected lookup of %d:%d: %+v, %v", tt.line, tt.column, pos, ok)
+		}
+	}
+
+	for _, invalid := range []string{
+		`{"version": 2, "sources": [], "mappings": ""}`,
+		`{"version": 3, "sources": [], "mappings": "AAAA"}`,
+		`{"version": 3, "sources": ["a.js"], "mappings": "A!"}`,
+		`{"version": 3, "sections": []}`,
+		`not json`,
+	} {
+		if _, err := v8.ParseSourceMap([]byte(invalid)); err == nil {
+			t.Errorf("expected error parsing %s", invalid)
+		}
+	}
+}
+
+func TestIsolateSetSourceMap(t *testing.T) {
+	t.Parallel()
+	iso := v8.NewIsolate()
+	defer iso.Dispose()
+	ctx := v8.NewContext(iso)
+	defer ctx.Close()
+
+	// every position of the first line of bundle.js maps to src/math.ts:11:3
+	sm, err := v8.ParseSourceMap([]byte(`{"version": 3, "sources": ["src/math.ts"], "names": [], "mappings": "AAUE"}`))
+	fatalIf(t, err)
+	iso.SetSourceMap("bundle.js", sm)
+
+	_, err = ctx.RunScript(`function fail() { throw new Error("boom") }`, "bundle.js")
+	fatalIf(t, err)
+	_, err = ctx.RunScript(`fail()`, "main.js")
+	var jsErr *v8.JSError
+	if !errors.As(err, &jsErr) {
+		t.Fatalf("expected a JSError, got %v", err)
+	}
+	if jsErr.Location != "src/math.ts:11:3" {
+		t.Errorf("unexpected location: %q", jsErr.Location)
+	}
+	if len(jsErr.Frames) != 2 || jsErr.Frames[0].ScriptName != "src/math.ts" || jsErr.Frames[0].Line != 11 || jsErr.Frames[1].ScriptName != "main.js" {
+		t.Errorf("unexpected frames: %+v", jsErr.Frames)
+	}
+	if !strings.Contains(jsErr.StackTrace, "at fail (src/math.ts:11:3)") || !strings.Contains(jsErr.StackTrace, "at main.js:1:1") {
+		t.Errorf("unexpected stack trace: %q", jsErr.StackTrace)
+	}
+
+	val, err := ctx.RunScript(`try { fail() } catch (e) { e.stack }`, "main.js")
+	fatalIf(t, err)
+	if s := val.String(); !strings.HasPrefix(s, "Error: boom\n    at fail (src/math.ts:11:3)") {
+		t.Errorf("unexpected stack: %q", s)
+	}
+
+	val, err = ctx.RunScript(`
+		Error.prepareStackTrace = (err, sites) => sites.map(s => s.getFileName()).join();
+		try { fail() } catch (e) { e.stack }
+	`, "main.js")
+	fatalIf(t, err)
+	if s := val.String(); s != "bundle.js,main.js" {
+		t.Errorf("unexpected stack from Error.prepareStackTrace: %q", s)
+	}
+
+	// origins ending with the origin of a source map are not mapped
+	_, err = ctx.RunScript(`throw new Error("other")`, "mybundle.js")
+	if !errors.As(err, &jsErr) || jsErr.Location != "mybundle.js:1:1" || !strings.Contains(jsErr.StackTrace, "at mybundle.js:1:7") {
+		t.Errorf("unexpected error from another script: %+v", err)
+	}
+
+	iso.SetSourceMap("bundle.js", nil)
+	_, err = ctx.RunScript(`delete Error.prepareStackTrace; fail()`, "main.js")
+	if !errors.As(err, &jsErr) || !strings.HasPrefix(jsErr.Location, "bundle.js:1:") {
+		t.Errorf("unexpected error without source map: %v", err)
+	}
+}
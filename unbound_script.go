@@ -7,7 +7,10 @@ package v8go
 // #include <stdlib.h>
 // #include "v8go.h"
 import "C"
-import "unsafe"
+import (
+	"context"
+	"unsafe"
+)
 
 type UnboundScript struct {
 	ptr C.UnboundScriptPtr
@@ -26,6 +29,14 @@ func (u *UnboundScript) Run(ctx *Context) (*Value, error) {
 	return valueResult(ctx, rtn)
 }
 
+// RunContext is like Run, but terminates the execution when ctx is cancelled
+// or its deadline is exceeded; the returned error then wraps ctx.Err().
+func (u *UnboundScript) RunContext(ctx context.Context, c *Context) (*Value, error) {
+	return u.iso.runWithContext(ctx, func() (*Value, error) {
+		return u.Run(c)
+	})
+}
+
 // Create a code cache from the unbound script.
 func (u *UnboundScript) CreateCodeCache() *CompilerCachedData {
 	rtn := C.UnboundScriptCreateCodeCache(u.iso.ptr, u.ptr)
@@ -6,18 +6,103 @@
 
 #include <stdio.h>
 
+#include <atomic>
+#include <chrono>
 #include <cstdlib>
 #include <cstring>
 #include <iostream>
+#include <memory>
 #include <sstream>
 #include <string>
 #include <unordered_map>
 #include <vector>
 
 #include "_cgo_export.h"
+#include "v8-inspector.h"
 
 using namespace v8;
 
+// V8's console builtins call the debug::ConsoleDelegate of the isolate, which
+// is not part of the public API. These declarations match
+// src/debug/interface-types.h and src/debug/debug-interface.h of the V8
+// version in deps/v8_version, and must be kept in sync when upgrading V8.
+namespace v8 {
+namespace debug {
+
+class ConsoleCallArguments : private FunctionCallbackInfo<Value> {
+ public:
+  int Length() const { return FunctionCallbackInfo<Value>::Length(); }
+  V8_INLINE Local<Value> operator[](int i) const {
+    return FunctionCallbackInfo<Value>::operator[](i);
+  }
+};
+
+class ConsoleContext {
+ public:
+  ConsoleContext(int id, Local<String> name) : id_(id), name_(name) {}
+  ConsoleContext() : id_(0) {}
+  int id() const { return id_; }
+  Local<String> name() const { return name_; }
+
+ private:
+  int id_;
+  Local<String> name_;
+};
+
+class ConsoleDelegate {
+ public:
+  virtual void Debug(const ConsoleCallArguments& args,
+                     const ConsoleContext& context) {}
+  virtual void Error(const ConsoleCallArguments& args,
+                     const ConsoleContext& context) {}
+  virtual void Info(const ConsoleCallArguments& args,
+                    const ConsoleContext& context) {}
+  virtual void Log(const ConsoleCallArguments& args,
+                   const ConsoleContext& context) {}
+  virtual void Warn(const ConsoleCallArguments& args,
+                    const ConsoleContext& context) {}
+  virtual void Dir(const ConsoleCallArguments& args,
+                   const ConsoleContext& context) {}
+  virtual void DirXml(const ConsoleCallArguments& args,
+                      const ConsoleContext& context) {}
+  virtual void Table(const ConsoleCallArguments& args,
+                     const ConsoleContext& context) {}
+  virtual void Trace(const ConsoleCallArguments& args,
+                     const ConsoleContext& context) {}
+  virtual void Group(const ConsoleCallArguments& args,
+                     const ConsoleContext& context) {}
+  virtual void GroupCollapsed(const ConsoleCallArguments& args,
+                              const ConsoleContext& context) {}
+  virtual void GroupEnd(const ConsoleCallArguments& args,
+                        const ConsoleContext& context) {}
+  virtual void Clear(const ConsoleCallArguments& args,
+                     const ConsoleContext& context) {}
+  virtual void Count(const ConsoleCallArguments& args,
+                     const ConsoleContext& context) {}
+  virtual void CountReset(const ConsoleCallArguments& args,
+                          const ConsoleContext& context) {}
+  virtual void Assert(const ConsoleCallArguments& args,
+                      const ConsoleContext& context) {}
+  virtual void Profile(const ConsoleCallArguments& args,
+                       const ConsoleContext& context) {}
+  virtual void ProfileEnd(const ConsoleCallArguments& args,
+                          const ConsoleContext& context) {}
+  virtual void Time(const ConsoleCallArguments& args,
+                    const ConsoleContext& context) {}
+  virtual void TimeLog(const ConsoleCallArguments& args,
+                       const ConsoleContext& context) {}
+  virtual void TimeEnd(const ConsoleCallArguments& args,
+                       const ConsoleContext& context) {}
+  virtual void TimeStamp(const ConsoleCallArguments& args,
+                         const ConsoleContext& context) {}
+  virtual ~ConsoleDelegate() = default;
+};
+
+void SetConsoleDelegate(Isolate* isolate, ConsoleDelegate* delegate);
+
+}  // namespace debug
+}  // namespace v8
+
 auto default_platform = platform::NewDefaultPlatform();
 ArrayBuffer::Allocator* default_allocator;
 
@@ -49,6 +134,42 @@ struct m_unboundScript {
   Persistent<UnboundScript> ptr;
 };
 
+struct m_module {
+  Isolate* iso;
+  Persistent<Module> ptr;
+};
+
+// isolate_data is the per-isolate state stored in data slot 1 of an Isolate.
+struct isolate_data {
+  int ref;
+  bool heap_limit_exceeded;
+  // Compiled modules keyed by their identity hash, so that the referrer of an
+  // import can be matched with its m_module.
+  std::unordered_multimap<int, m_module*> modules;
+  // The snapshot the isolate was created from; V8 keeps a reference to it to
+  // deserialize contexts for the lifetime of the isolate.
+  StartupData* startup_data;
+  // The delegate set by IsolateSetConsoleDelegate, if any.
+  debug::ConsoleDelegate* console_delegate;
+  // The garbage collection counters by GC type, which are read from other
+  // threads, and when the current collection of each type started.
+  std::atomic<uint64_t> gc_count[GC_TYPE_COUNT];
+  std::atomic<int64_t> gc_total_pause_ns[GC_TYPE_COUNT];
+  std::chrono::steady_clock::time_point gc_start[GC_TYPE_COUNT];
+  // Whether the Go GC callbacks are called, see IsolateSetGCCallbacks.
+  std::atomic<bool> gc_callbacks;
+};
+
+static inline isolate_data* isolateData(Isolate* iso) {
+  return static_cast<isolate_data*>(iso->GetData(1));
+}
+
+struct m_snapshotCreator {
+  SnapshotCreator* ptr;
+  Isolate* iso;
+  bool default_context_set;
+};
+
 const char* CopyString(std::string str) {
   int len = str.length();
   char* mem = (char*)malloc(len + 1);
@@ -64,14 +185,41 @@ const char* CopyString(String::Utf8Value& value) {
   return CopyString(std::string(*value, value.length()));
 }
 
+m_value* tracked_value(m_ctx* ctx, m_value* val) {
+  // (rogchap) we track values against a context so that when the context is
+  // closed (either manually or GC'd by Go) we can also release all the
+  // values associated with the context;
+  if (val->id == 0) {
+    val->id = ++ctx->nextValId;
+    ctx->vals[val->id] = val;
+  }
+
+  return val;
+}
+
+static inline m_ctx* isolateInternalContext(Isolate* iso) {
+  return static_cast<m_ctx*>(iso->GetData(0));
+}
+
 static RtnError ExceptionError(TryCatch& try_catch,
                                Isolate* iso,
                                Local<Context> ctx) {
   HandleScope handle_scope(iso);
 
   RtnError rtn = {nullptr, nullptr, nullptr};
+  rtn.isolateRef = isolateData(iso)->ref;
 
   if (try_catch.HasTerminated()) {
+    rtn.terminated = 1;
+    isolate_data* iso_data = isolateData(iso);
+    if (iso_data->heap_limit_exceeded) {
+      rtn.heapLimitExceeded = 1;
+      // Once the outermost call has returned the isolate can be used again,
+      // and the next time the limit is near Go decides again.
+      if (!iso->IsExecutionTerminating()) {
+        iso_data->heap_limit_exceeded = false;
+      }
+    }
     rtn.msg =
         CopyString("ExecutionTerminated: script execution has been terminated");
     return rtn;
@@ -103,19 +251,72 @@ static RtnError ExceptionError(TryCatch& try_catch,
     rtn.stack = CopyString(stack);
   }
 
+  // The exception is tracked in the context it was caught in, so that it can
+  // be used until that context is closed. Exceptions caught in the internal
+  // context, which is never closed, are not kept.
+  Local<Value> exc = try_catch.Exception();
+  if (ctx != isolateInternalContext(iso)->ptr.Get(iso)) {
+    rtn.exceptionCtxRef = ctx->GetEmbedderData(1).As<Integer>()->Value();
+    m_ctx* exc_ctx = goContext(rtn.exceptionCtxRef);
+    m_value* exc_val = new m_value;
+    exc_val->id = 0;
+    exc_val->iso = iso;
+    exc_val->ctx = exc_ctx;
+    exc_val->ptr =
+        Persistent<Value, CopyablePersistentTraits<Value>>(iso, exc);
+    rtn.exception = tracked_value(exc_ctx, exc_val);
+  }
+
+  if (exc->IsObject()) {
+    Local<Value> name;
+    if (exc.As<Object>()
+            ->Get(ctx, String::NewFromUtf8Literal(iso, "name"))
+            .ToLocal(&name) &&
+        name->IsString()) {
+      String::Utf8Value name_str(iso, name);
+      rtn.name = CopyString(name_str);
+    }
+  }
+
+  // The stack trace captured when the exception was thrown, or when the
+  // error was created if it was thrown from outside JS.
+  Local<StackTrace> trace;
+  if (!msg.IsEmpty()) {
+    trace = msg->GetStackTrace();
+  }
+  if (trace.IsEmpty()) {
+    trace = Exception::GetStackTrace(exc);
+  }
+  if (!trace.IsEmpty() && trace->GetFrameCount() > 0) {
+    int count = trace->GetFrameCount();
+    rtn.frames = new ErrorStackFrame[count];
+    rtn.frameCount = count;
+    for (int i = 0; i < count; i++) {
+      Local<StackFrame> frame = trace->GetFrame(iso, i);
+      String::Utf8Value script_name(iso, frame->GetScriptNameOrSourceURL());
+      String::Utf8Value function_name(iso, frame->GetFunctionName());
+      rtn.frames[i] = ErrorStackFrame{
+          CopyString(script_name),
+          CopyString(function_name),
+          frame->GetLineNumber(),
+          frame->GetColumn(),
+          frame->GetScriptId(),
+          frame->IsEval(),
+          frame->IsConstructor(),
+          frame->IsWasm(),
+      };
+    }
+  }
+
   return rtn;
 }
 
-m_value* tracked_value(m_ctx* ctx, m_value* val) {
-  // (rogchap) we track values against a context so that when the context is
-  // closed (either manually or GC'd by Go) we can also release all the
-  // values associated with the context;
-  if (val->id == 0) {
-    val->id = ++ctx->nextValId;
-    ctx->vals[val->id] = val;
+void ErrorStackFramesDelete(ErrorStackFrame* frames, int count) {
+  for (int i = 0; i < count; i++) {
+    free((void*)frames[i].scriptName);
+    free((void*)frames[i].functionName);
   }
-
-  return val;
+  delete[] frames;
 }
 
 m_unboundScript* tracked_unbound_script(m_ctx* ctx, m_unboundScript* us) {
@@ -126,6 +327,52 @@ m_unboundScript* tracked_unbound_script(m_ctx* ctx, m_unboundScript* us) {
 
 extern "C" {
 
+static void FunctionTemplateCallback(const FunctionCallbackInfo<Value>& info);
+static void PropertyAccessorGetter(Local<Name> property,
+                                   const PropertyCallbackInfo<Value>& info);
+static void PropertyAccessorSetter(Local<Name> property,
+                                   Local<Value> value,
+                                   const PropertyCallbackInfo<void>& info);
+static void InterceptNamedGetter(Local<Name> property,
+                                 const PropertyCallbackInfo<Value>& info);
+static void InterceptNamedSetter(Local<Name> property,
+                                 Local<Value> value,
+                                 const PropertyCallbackInfo<Value>& info);
+static void InterceptNamedQuery(Local<Name> property,
+                                const PropertyCallbackInfo<Integer>& info);
+static void InterceptNamedDeleter(Local<Name> property,
+                                  const PropertyCallbackInfo<Boolean>& info);
+static void InterceptNamedEnumerator(const PropertyCallbackInfo<Array>& info);
+static void InterceptIndexedGetter(uint32_t index,
+                                   const PropertyCallbackInfo<Value>& info);
+static void InterceptIndexedSetter(uint32_t index,
+                                   Local<Value> value,
+                                   const PropertyCallbackInfo<Value>& info);
+static void InterceptIndexedQuery(uint32_t index,
+                                  const PropertyCallbackInfo<Integer>& info);
+static void InterceptIndexedDeleter(uint32_t index,
+                                    const PropertyCallbackInfo<Boolean>& info);
+static void InterceptIndexedEnumerator(const PropertyCallbackInfo<Array>& info);
+
+// The C++ callbacks that functions and templates can refer to; these need to
+// be known when creating or deserializing a snapshot.
+static const intptr_t external_references[] = {
+    reinterpret_cast<intptr_t>(FunctionTemplateCallback),
+    reinterpret_cast<intptr_t>(PropertyAccessorGetter),
+    reinterpret_cast<intptr_t>(PropertyAccessorSetter),
+    reinterpret_cast<intptr_t>(InterceptNamedGetter),
+    reinterpret_cast<intptr_t>(InterceptNamedSetter),
+    reinterpret_cast<intptr_t>(InterceptNamedQuery),
+    reinterpret_cast<intptr_t>(InterceptNamedDeleter),
+    reinterpret_cast<intptr_t>(InterceptNamedEnumerator),
+    reinterpret_cast<intptr_t>(InterceptIndexedGetter),
+    reinterpret_cast<intptr_t>(InterceptIndexedSetter),
+    reinterpret_cast<intptr_t>(InterceptIndexedQuery),
+    reinterpret_cast<intptr_t>(InterceptIndexedDeleter),
+    reinterpret_cast<intptr_t>(InterceptIndexedEnumerator),
+    0,
+};
+
 /********** Isolate **********/
 
 #define ISOLATE_SCOPE(iso)           \
@@ -148,27 +395,161 @@ void Init() {
   return;
 }
 
-IsolatePtr NewIsolate() {
-  Isolate::CreateParams params;
-  params.array_buffer_allocator = default_allocator;
-  Isolate* iso = Isolate::New(params);
+static size_t IsolateNearHeapLimitCallback(void* data,
+                                           size_t current_heap_limit,
+                                           size_t initial_heap_limit) {
+  Isolate* iso = static_cast<Isolate*>(data);
+  isolate_data* iso_data = isolateData(iso);
+
+  if (!iso_data->heap_limit_exceeded) {
+    size_t limit = goNearHeapLimitCallback(iso_data->ref, current_heap_limit,
+                                           initial_heap_limit);
+    if (limit > current_heap_limit) {
+      return limit;
+    }
+    iso_data->heap_limit_exceeded = true;
+    iso->TerminateExecution();
+  }
+
+  // Give V8 some headroom to unwind the stack after the termination, otherwise
+  // it will abort the process with an out-of-memory error.
+  return current_heap_limit + current_heap_limit / 4;
+}
+
+// Returns the index of a GC type in the counters of isolate_data.
+static int GCTypeIndex(GCType type) {
+  for (int i = 0; i < GC_TYPE_COUNT; ++i) {
+    if (type == (1 << i)) {
+      return i;
+    }
+  }
+  return -1;
+}
+
+static void IsolateGCPrologueCallback(Isolate* iso,
+                                      GCType type,
+                                      GCCallbackFlags flags) {
+  isolate_data* iso_data = isolateData(iso);
+  int i = GCTypeIndex(type);
+  if (i < 0) {
+    return;
+  }
+  iso_data->gc_start[i] = std::chrono::steady_clock::now();
+  if (iso_data->gc_callbacks) {
+    goGCCallback(iso_data->ref, 0, type, flags, 0);
+  }
+}
+
+static void IsolateGCEpilogueCallback(Isolate* iso,
+                                      GCType type,
+                                      GCCallbackFlags flags) {
+  isolate_data* iso_data = isolateData(iso);
+  int i = GCTypeIndex(type);
+  if (i < 0) {
+    return;
+  }
+  int64_t pause = std::chrono::duration_cast<std::chrono::nanoseconds>(
+                      std::chrono::steady_clock::now() - iso_data->gc_start[i])
+                      .count();
+  iso_data->gc_count[i]++;
+  iso_data->gc_total_pause_ns[i] += pause;
+  if (iso_data->gc_callbacks) {
+    goGCCallback(iso_data->ref, 1, type, flags, pause);
+  }
+}
+
+static void InitIsolate(Isolate* iso, int ref) {
   Locker locker(iso);
   Isolate::Scope isolate_scope(iso);
   HandleScope handle_scope(iso);
 
-  iso->SetCaptureStackTraceForUncaughtExceptions(true);
+  // Detailed stack traces are needed for the frames of JSError.
+  iso->SetCaptureStackTraceForUncaughtExceptions(true, 10,
+                                                 StackTrace::kDetailed);
+
+  isolate_data* iso_data = new isolate_data;
+  iso_data->ref = ref;
+  iso_data->heap_limit_exceeded = false;
+  iso_data->startup_data = nullptr;
+  iso_data->console_delegate = nullptr;
+  for (int i = 0; i < GC_TYPE_COUNT; ++i) {
+    iso_data->gc_count[i] = 0;
+    iso_data->gc_total_pause_ns[i] = 0;
+  }
+  iso_data->gc_callbacks = false;
+  iso->SetData(1, iso_data);
+  iso->AddNearHeapLimitCallback(IsolateNearHeapLimitCallback, iso);
+  iso->AddGCPrologueCallback(IsolateGCPrologueCallback);
+  iso->AddGCEpilogueCallback(IsolateGCEpilogueCallback);
 
   // Create a Context for internal use
   m_ctx* ctx = new m_ctx;
   ctx->ptr.Reset(iso, Context::New(iso));
   ctx->iso = iso;
   iso->SetData(0, ctx);
-
-  return iso;
 }
 
-static inline m_ctx* isolateInternalContext(Isolate* iso) {
-  return static_cast<m_ctx*>(iso->GetData(0));
+IsolatePtr NewIsolate(IsolateConstraints* constraints,
+                      const char* snapshot_blob,
+                      int snapshot_blob_size,
+                      int ref) {
+  Isolate::CreateParams params;
+  params.array_buffer_allocator = default_allocator;
+
+  if (constraints != nullptr) {
+    ResourceConstraints& rc = params.constraints;
+    if (constraints->max_heap_size > 0) {
+      rc.ConfigureDefaultsFromHeapSize(constraints->initial_heap_size,
+                                       constraints->max_heap_size);
+    }
+    if (constraints->max_old_generation_size > 0) {
+      rc.set_max_old_generation_size_in_bytes(
+          constraints->max_old_generation_size);
+    }
+    if (constraints->max_young_generation_size > 0) {
+      rc.set_max_young_generation_size_in_bytes(
+          constraints->max_young_generation_size);
+    }
+    if (constraints->initial_old_generation_size > 0) {
+      rc.set_initial_old_generation_size_in_bytes(
+          constraints->initial_old_generation_size);
+    }
+    if (constraints->initial_young_generation_size > 0) {
+      rc.set_initial_young_generation_size_in_bytes(
+          constraints->initial_young_generation_size);
+    }
+    if (constraints->code_range_size > 0) {
+      rc.set_code_range_size_in_bytes(constraints->code_range_size);
+    }
+
+    // Write back the effective values so that they can be reported
+    // alongside the heap statistics; ConfigureDefaultsFromHeapSize derives the
+    // generation sizes from the total heap size.
+    constraints->max_old_generation_size =
+        rc.max_old_generation_size_in_bytes();
+    constraints->max_young_generation_size =
+        rc.max_young_generation_size_in_bytes();
+    constraints->initial_old_generation_size =
+        rc.initial_old_generation_size_in_bytes();
+    constraints->initial_young_generation_size =
+        rc.initial_young_generation_size_in_bytes();
+    constraints->code_range_size = rc.code_range_size_in_bytes();
+  }
+
+  StartupData* startup_data = nullptr;
+  if (snapshot_blob != nullptr) {
+    char* data = new char[snapshot_blob_size];
+    memcpy(data, snapshot_blob, snapshot_blob_size);
+    startup_data = new StartupData{data, snapshot_blob_size};
+    params.snapshot_blob = startup_data;
+    params.external_references = external_references;
+  }
+
+  Isolate* iso = Isolate::New(params);
+  InitIsolate(iso, ref);
+  isolateData(iso)->startup_data = startup_data;
+
+  return iso;
 }
 
 void IsolatePerformMicrotaskCheckpoint(IsolatePtr iso) {
@@ -176,11 +557,35 @@ void IsolatePerformMicrotaskCheckpoint(IsolatePtr iso) {
   iso->PerformMicrotaskCheckpoint();
 }
 
+static void IsolateFreeData(Isolate* iso) {
+  ContextFree(isolateInternalContext(iso));
+  iso->RemoveNearHeapLimitCallback(IsolateNearHeapLimitCallback, 0);
+  iso->RemoveGCPrologueCallback(IsolateGCPrologueCallback);
+  iso->RemoveGCEpilogueCallback(IsolateGCEpilogueCallback);
+
+  isolate_data* iso_data = isolateData(iso);
+  for (auto it = iso_data->modules.begin(); it != iso_data->modules.end();
+       ++it) {
+    m_module* mod = it->second;
+    mod->ptr.Reset();
+    delete mod;
+  }
+  if (iso_data->console_delegate != nullptr) {
+    debug::SetConsoleDelegate(iso, nullptr);
+    delete iso_data->console_delegate;
+  }
+  if (iso_data->startup_data != nullptr) {
+    delete[] iso_data->startup_data->data;
+    delete iso_data->startup_data;
+  }
+  delete iso_data;
+}
+
 void IsolateDispose(IsolatePtr iso) {
   if (iso == nullptr) {
     return;
   }
-  ContextFree(isolateInternalContext(iso));
+  IsolateFreeData(iso);
 
   iso->Dispose();
 }
@@ -189,6 +594,75 @@ void IsolateTerminateExecution(IsolatePtr iso) {
   iso->TerminateExecution();
 }
 
+void IsolateCancelTerminateExecution(IsolatePtr iso) {
+  iso->CancelTerminateExecution();
+  isolateData(iso)->heap_limit_exceeded = false;
+}
+
+// Formats the stack of errors like V8 does by default, with the positions of
+// scripts with a source map mapped by Go. A function set as
+// Error.prepareStackTrace by scripts is used instead, as it is by V8.
+static MaybeLocal<Value> PrepareStackTrace(Local<Context> context,
+                                           Local<Value> error,
+                                           Local<Array> sites) {
+  Isolate* iso = context->GetIsolate();
+  EscapableHandleScope handle_scope(iso);
+
+  Local<Value> error_ctor;
+  if (context->Global()
+          ->Get(context, String::NewFromUtf8Literal(iso, "Error"))
+          .ToLocal(&error_ctor) &&
+      error_ctor->IsObject()) {
+    Local<Value> prepare;
+    if (error_ctor.As<Object>()
+            ->Get(context,
+                  String::NewFromUtf8Literal(iso, "prepareStackTrace"))
+            .ToLocal(&prepare) &&
+        prepare->IsFunction()) {
+      Local<Value> args[] = {error, sites};
+      Local<Value> result;
+      if (!prepare.As<Function>()
+               ->Call(context, error_ctor, 2, args)
+               .ToLocal(&result)) {
+        return MaybeLocal<Value>();
+      }
+      return handle_scope.Escape(result);
+    }
+  }
+
+  Local<String> header;
+  if (!error->ToString(context).ToLocal(&header)) {
+    return MaybeLocal<Value>();
+  }
+  std::ostringstream sb;
+  sb << *String::Utf8Value(iso, header);
+  for (uint32_t i = 0; i < sites->Length(); i++) {
+    Local<Value> site;
+    Local<String> site_str;
+    if (!sites->Get(context, i).ToLocal(&site) ||
+        !site->ToString(context).ToLocal(&site_str)) {
+      return MaybeLocal<Value>();
+    }
+    sb << "\n    at " << *String::Utf8Value(iso, site_str);
+  }
+
+  std::string stack = sb.str();
+  char* mapped = goMapStackTrace(isolateData(iso)->ref, (char*)stack.c_str());
+  if (mapped != nullptr) {
+    stack = mapped;
+    free(mapped);
+  }
+  Local<String> result =
+      String::NewFromUtf8(iso, stack.c_str(), NewStringType::kNormal,
+                          stack.length())
+          .ToLocalChecked();
+  return handle_scope.Escape(result);
+}
+
+void IsolateSetPrepareStackTraceCallback(IsolatePtr iso) {
+  iso->SetPrepareStackTraceCallback(PrepareStackTrace);
+}
+
 int IsolateIsExecutionTerminating(IsolatePtr iso) {
   return iso->IsExecutionTerminating();
 }
@@ -213,6 +687,87 @@ IsolateHStatistics IsolationGetHeapStatistics(IsolatePtr iso) {
                             hs.number_of_detached_contexts()};
 }
 
+IsolateGCStatistics IsolateGetGCStatistics(IsolatePtr iso) {
+  IsolateGCStatistics stats = {};
+  if (iso == nullptr) {
+    return stats;
+  }
+  isolate_data* iso_data = isolateData(iso);
+  for (int i = 0; i < GC_TYPE_COUNT; ++i) {
+    stats.count[i] = iso_data->gc_count[i];
+    stats.total_pause_ns[i] = iso_data->gc_total_pause_ns[i];
+  }
+  return stats;
+}
+
+void IsolateSetGCCallbacks(IsolatePtr iso, int enabled) {
+  if (iso == nullptr) {
+    return;
+  }
+  isolateData(iso)->gc_callbacks = enabled;
+}
+
+size_t IsolateNumberOfHeapSpaces(IsolatePtr iso) {
+  if (iso == nullptr) {
+    return 0;
+  }
+  return iso->NumberOfHeapSpaces();
+}
+
+// Returns statistics without name if the index is out of range.
+IsolateHeapSpaceStatistics IsolateGetHeapSpaceStatistics(IsolatePtr iso,
+                                                         size_t index) {
+  v8::HeapSpaceStatistics hs;
+  if (iso == nullptr || !iso->GetHeapSpaceStatistics(&hs, index)) {
+    return IsolateHeapSpaceStatistics{nullptr};
+  }
+  return IsolateHeapSpaceStatistics{hs.space_name(), hs.space_size(),
+                                    hs.space_used_size(),
+                                    hs.space_available_size(),
+                                    hs.physical_space_size()};
+}
+
+IsolateHeapCodeStatistics IsolateGetHeapCodeStatistics(IsolatePtr iso) {
+  if (iso == nullptr) {
+    return IsolateHeapCodeStatistics{0};
+  }
+  Locker locker(iso);
+  Isolate::Scope isolate_scope(iso);
+  v8::HeapCodeStatistics hs;
+  if (!iso->GetHeapCodeAndMetadataStatistics(&hs)) {
+    return IsolateHeapCodeStatistics{0};
+  }
+  return IsolateHeapCodeStatistics{
+      hs.code_and_metadata_size(), hs.bytecode_and_metadata_size(),
+      hs.external_script_source_size(), hs.cpu_profiler_metadata_size()};
+}
+
+size_t IsolateNumberOfTrackedHeapObjectTypes(IsolatePtr iso) {
+  if (iso == nullptr) {
+    return 0;
+  }
+  Locker locker(iso);
+  Isolate::Scope isolate_scope(iso);
+  return iso->NumberOfTrackedHeapObjectTypes();
+}
+
+// Returns statistics without type if they are not tracked, which requires
+// the --track-gc-object-stats flag.
+IsolateHeapObjectStatistics IsolateGetHeapObjectStatistics(IsolatePtr iso,
+                                                           size_t index) {
+  if (iso == nullptr) {
+    return IsolateHeapObjectStatistics{nullptr};
+  }
+  Locker locker(iso);
+  Isolate::Scope isolate_scope(iso);
+  v8::HeapObjectStatistics hs;
+  if (!iso->GetHeapObjectStatisticsAtLastGC(&hs, index)) {
+    return IsolateHeapObjectStatistics{nullptr};
+  }
+  return IsolateHeapObjectStatistics{hs.object_type(), hs.object_sub_type(),
+                                     hs.object_count(), hs.object_size()};
+}
+
 RtnUnboundScript IsolateCompileUnboundScript(IsolatePtr iso,
                                              const char* s,
                                              const char* o,
@@ -278,6 +833,63 @@ ValuePtr IsolateThrowException(IsolatePtr iso, ValuePtr value) {
   return tracked_value(ctx, new_val);
 }
 
+ValuePtr NewValueError(IsolatePtr iso,
+                       ErrorKind kind,
+                       const char* message,
+                       ValuePtr cause) {
+  Locker locker(iso);
+  Isolate::Scope isolate_scope(iso);
+  HandleScope handle_scope(iso);
+
+  // Errors are created in the current context when called from a callback,
+  // so that they have its Error constructors as prototypes; `instanceof`
+  // would otherwise be false in scripts.
+  m_ctx* ctx;
+  Local<Context> local_ctx;
+  if (iso->InContext()) {
+    local_ctx = iso->GetCurrentContext();
+    ctx = goContext(local_ctx->GetEmbedderData(1).As<Integer>()->Value());
+  } else {
+    ctx = isolateInternalContext(iso);
+    local_ctx = ctx->ptr.Get(iso);
+  }
+  Context::Scope context_scope(local_ctx);
+
+  Local<String> msg =
+      String::NewFromUtf8(iso, message, NewStringType::kNormal)
+          .ToLocalChecked();
+  Local<Value> v;
+  switch (kind) {
+    case ERROR_RANGE:
+      v = Exception::RangeError(msg);
+      break;
+    case ERROR_REFERENCE:
+      v = Exception::ReferenceError(msg);
+      break;
+    case ERROR_SYNTAX:
+      v = Exception::SyntaxError(msg);
+      break;
+    case ERROR_TYPE:
+      v = Exception::TypeError(msg);
+      break;
+    default:
+      v = Exception::Error(msg);
+  }
+  if (cause != nullptr) {
+    Local<String> cause_key = String::NewFromUtf8Literal(iso, "cause");
+    v.As<Object>()
+        ->DefineOwnProperty(local_ctx, cause_key, cause->ptr.Get(iso), DontEnum)
+        .Check();
+  }
+
+  m_value* val = new m_value;
+  val->id = 0;
+  val->iso = iso;
+  val->ctx = ctx;
+  val->ptr = Persistent<Value, CopyablePersistentTraits<Value>>(iso, v);
+  return tracked_value(ctx, val);
+}
+
 /********** CpuProfiler **********/
 
 CPUProfiler* NewCPUProfiler(IsolatePtr iso_ptr) {
@@ -316,6 +928,33 @@ void CPUProfilerStartProfiling(CPUProfiler* profiler, const char* title) {
   profiler->ptr->StartProfiling(title_str);
 }
 
+// Returns whether the profile was started, or else 0 if too many profiles
+// are being collected.
+int CPUProfilerStartProfilingWithOptions(CPUProfiler* profiler,
+                                         const char* title,
+                                         CPUProfilingOptions options) {
+  if (profiler->iso == nullptr) {
+    return 0;
+  }
+
+  Locker locker(profiler->iso);
+  Isolate::Scope isolate_scope(profiler->iso);
+  HandleScope handle_scope(profiler->iso);
+
+  Local<String> title_str =
+      String::NewFromUtf8(profiler->iso, title, NewStringType::kNormal)
+          .ToLocalChecked();
+  MaybeLocal<Context> filter_context;
+  if (options.filterContext != nullptr) {
+    filter_context = options.filterContext->ptr.Get(profiler->iso);
+  }
+  CpuProfilingStatus status = profiler->ptr->StartProfiling(
+      title_str,
+      CpuProfilingOptions((CpuProfilingMode)options.mode, options.maxSamples,
+                          options.samplingIntervalUs, filter_context));
+  return status != CpuProfilingStatus::kErrorTooManyProfilers;
+}
+
 CPUProfileNode* NewCPUProfileNode(const CpuProfileNode* ptr_) {
   int count = ptr_->GetChildrenCount();
   CPUProfileNode** children = new CPUProfileNode*[count];
@@ -323,6 +962,20 @@ CPUProfileNode* NewCPUProfileNode(const CpuProfileNode* ptr_) {
     children[i] = NewCPUProfileNode(ptr_->GetChild(i));
   }
 
+  unsigned int line_count = ptr_->GetHitLineCount();
+  CPUProfileLineTick* line_ticks = new CPUProfileLineTick[line_count];
+  if (line_count > 0) {
+    CpuProfileNode::LineTick* entries =
+        new CpuProfileNode::LineTick[line_count];
+    if (!ptr_->GetLineTicks(entries, line_count)) {
+      line_count = 0;
+    }
+    for (unsigned int i = 0; i < line_count; ++i) {
+      line_ticks[i] = CPUProfileLineTick{entries[i].line, entries[i].hit_count};
+    }
+    delete[] entries;
+  }
+
   CPUProfileNode* root = new CPUProfileNode{
       ptr_,
       ptr_->GetNodeId(),
@@ -333,6 +986,8 @@ CPUProfileNode* NewCPUProfileNode(const CpuProfileNode* ptr_) {
       ptr_->GetColumnNumber(),
       ptr_->GetHitCount(),
       ptr_->GetBailoutReason(),
+      (int)line_count,
+      line_ticks,
       count,
       children,
   };
@@ -365,6 +1020,15 @@ CPUProfile* CPUProfilerStopProfiling(CPUProfiler* profiler, const char* title) {
   profile->startTime = profile->ptr->GetStartTime();
   profile->endTime = profile->ptr->GetEndTime();
 
+  int count = profile->ptr->GetSamplesCount();
+  profile->samplesCount = count;
+  profile->sampleNodeIds = new unsigned[count];
+  profile->sampleTimestamps = new int64_t[count];
+  for (int i = 0; i < count; ++i) {
+    profile->sampleNodeIds[i] = profile->ptr->GetSample(i)->GetNodeId();
+    profile->sampleTimestamps[i] = profile->ptr->GetSampleTimestamp(i);
+  }
+
   return profile;
 }
 
@@ -374,6 +1038,7 @@ void CPUProfileNodeDelete(CPUProfileNode* node) {
   }
 
   delete[] node->children;
+  delete[] node->lineTicks;
   delete node;
 }
 
@@ -385,10 +1050,212 @@ void CPUProfileDelete(CPUProfile* profile) {
   free((void*)profile->title);
 
   CPUProfileNodeDelete(profile->root);
+  delete[] profile->sampleNodeIds;
+  delete[] profile->sampleTimestamps;
 
   delete profile;
 }
 
+/********** HeapProfiler **********/
+
+// Returns whether sampling started, or else 0 if it is already running.
+int HeapProfilerStartSampling(IsolatePtr iso,
+                              uint64_t sample_interval,
+                              int stack_depth,
+                              int flags) {
+  Locker locker(iso);
+  Isolate::Scope isolate_scope(iso);
+
+  return iso->GetHeapProfiler()->StartSamplingHeapProfiler(
+      sample_interval, stack_depth, (HeapProfiler::SamplingFlags)flags);
+}
+
+void HeapProfilerStopSampling(IsolatePtr iso) {
+  Locker locker(iso);
+  Isolate::Scope isolate_scope(iso);
+
+  iso->GetHeapProfiler()->StopSamplingHeapProfiler();
+}
+
+AllocationProfileNode* NewAllocationProfileNode(Isolate* iso,
+                                                AllocationProfile::Node* ptr_) {
+  int count = ptr_->children.size();
+  AllocationProfileNode** children = new AllocationProfileNode*[count];
+  for (int i = 0; i < count; ++i) {
+    children[i] = NewAllocationProfileNode(iso, ptr_->children[i]);
+  }
+
+  int allocations_count = ptr_->allocations.size();
+  AllocationProfileAllocation* allocations =
+      new AllocationProfileAllocation[allocations_count];
+  for (int i = 0; i < allocations_count; ++i) {
+    allocations[i] = AllocationProfileAllocation{
+        ptr_->allocations[i].size, ptr_->allocations[i].count};
+  }
+
+  String::Utf8Value script_name(iso, ptr_->script_name);
+  String::Utf8Value name(iso, ptr_->name);
+  AllocationProfileNode* node = new AllocationProfileNode{
+      ptr_->node_id,
+      ptr_->script_id,
+      CopyString(script_name),
+      CopyString(name),
+      ptr_->line_number,
+      ptr_->column_number,
+      allocations_count,
+      allocations,
+      count,
+      children,
+  };
+  return node;
+}
+
+// Returns the call tree of the sampled allocations that are still live, or
+// nullptr if sampling is not running.
+AllocationProfileNode* HeapProfilerGetAllocationProfile(IsolatePtr iso) {
+  Locker locker(iso);
+  Isolate::Scope isolate_scope(iso);
+  HandleScope handle_scope(iso);
+
+  std::unique_ptr<AllocationProfile> profile(
+      iso->GetHeapProfiler()->GetAllocationProfile());
+  if (!profile) {
+    return nullptr;
+  }
+  return NewAllocationProfileNode(iso, profile->GetRootNode());
+}
+
+void AllocationProfileNodeDelete(AllocationProfileNode* node) {
+  for (int i = 0; i < node->childrenCount; ++i) {
+    AllocationProfileNodeDelete(node->children[i]);
+  }
+
+  free((void*)node->scriptResourceName);
+  free((void*)node->functionName);
+  delete[] node->allocations;
+  delete[] node->children;
+  delete node;
+}
+
+// Streams the serialized snapshot to the Go writer in chunks.
+class GoHeapSnapshotStream : public OutputStream {
+ public:
+  explicit GoHeapSnapshotStream(int writer_ref) : writer_ref_(writer_ref) {}
+
+  void EndOfStream() override {}
+
+  int GetChunkSize() override { return 64 * 1024; }
+
+  WriteResult WriteAsciiChunk(char* data, int size) override {
+    if (!goHeapSnapshotWrite(writer_ref_, data, size)) {
+      return kAbort;
+    }
+    return kContinue;
+  }
+
+ private:
+  int writer_ref_;
+};
+
+// Names the global objects of the contexts of v8go in the snapshot.
+class GoObjectNameResolver : public HeapProfiler::ObjectNameResolver {
+ public:
+  explicit GoObjectNameResolver(int writer_ref) : writer_ref_(writer_ref) {}
+
+  ~GoObjectNameResolver() override {
+    for (char* name : names_) {
+      free(name);
+    }
+  }
+
+  const char* GetName(Local<Object> object) override {
+    Local<Context> local_ctx;
+    if (!object->GetCreationContext().ToLocal(&local_ctx) ||
+        local_ctx->GetNumberOfEmbedderDataFields() < 2) {
+      return nullptr;
+    }
+    Local<Value> ref = local_ctx->GetEmbedderData(1);
+    if (!ref->IsInt32()) {
+      return nullptr;
+    }
+    char* name = goHeapSnapshotGlobalObjectName(writer_ref_,
+                                                ref.As<Integer>()->Value());
+    if (name != nullptr) {
+      // The names must stay alive until the snapshot is taken.
+      names_.push_back(name);
+    }
+    return name;
+  }
+
+ private:
+  int writer_ref_;
+  std::vector<char*> names_;
+};
+
+// GoObjectNode is an embedder node that names the V8 object it wraps: the
+// snapshot shows the object under the name of the node.
+class GoObjectNode : public EmbedderGraph::Node {
+ public:
+  GoObjectNode(const char* name, EmbedderGraph::Node* wrapper)
+      : name_(name), wrapper_(wrapper) {}
+
+  const char* Name() override { return name_; }
+  size_t SizeInBytes() override { return 0; }
+  Node* WrapperNode() override { return wrapper_; }
+
+ private:
+  const char* name_;
+  EmbedderGraph::Node* wrapper_;
+};
+
+static void BuildGoObjectGraph(Isolate* iso,
+                               EmbedderGraph* graph,
+                               void* data) {
+  HeapSnapshotOptions* options = static_cast<HeapSnapshotOptions*>(data);
+  HandleScope handle_scope(iso);
+  for (int i = 0; i < options->objectNamesCount; i++) {
+    HeapSnapshotObjectName* object_name = &options->objectNames[i];
+    Local<Value> object = object_name->object->ptr.Get(iso);
+    EmbedderGraph::Node* wrapper = graph->V8Node(object);
+    graph->AddNode(std::unique_ptr<EmbedderGraph::Node>(
+        new GoObjectNode(object_name->name, wrapper)));
+  }
+}
+
+void HeapProfilerTakeHeapSnapshot(IsolatePtr iso,
+                                  int writer_ref,
+                                  HeapSnapshotOptions options) {
+  Locker locker(iso);
+  Isolate::Scope isolate_scope(iso);
+  HandleScope handle_scope(iso);
+
+  GoObjectNameResolver resolver(writer_ref);
+  HeapProfiler::HeapSnapshotOptions snapshot_options;
+  if (options.nameGlobalObjects) {
+    snapshot_options.global_object_name_resolver = &resolver;
+  }
+  if (options.exposeInternals) {
+    snapshot_options.snapshot_mode =
+        HeapProfiler::HeapSnapshotMode::kExposeInternals;
+  }
+  if (options.exposeNumericValues) {
+    snapshot_options.numerics_mode =
+        HeapProfiler::NumericsMode::kExposeNumericValues;
+  }
+
+  HeapProfiler* profiler = iso->GetHeapProfiler();
+  if (options.objectNamesCount > 0) {
+    profiler->AddBuildEmbedderGraphCallback(BuildGoObjectGraph, &options);
+  }
+  const HeapSnapshot* snapshot = profiler->TakeHeapSnapshot(snapshot_options);
+  if (options.objectNamesCount > 0) {
+    profiler->RemoveBuildEmbedderGraphCallback(BuildGoObjectGraph, &options);
+  }
+  GoHeapSnapshotStream stream(writer_ref);
+  snapshot->Serialize(&stream, HeapSnapshot::kJSON);
+  const_cast<HeapSnapshot*>(snapshot)->Delete();
+}
+
 /********** Template **********/
 
 #define LOCAL_TEMPLATE(tmpl_ptr)     \
@@ -438,42 +1305,430 @@ TemplatePtr NewObjectTemplate(IsolatePtr iso) {
   return ot;
 }
 
-RtnValue ObjectTemplateNewInstance(TemplatePtr ptr, ContextPtr ctx) {
-  LOCAL_TEMPLATE(ptr);
-  TryCatch try_catch(iso);
-  Local<Context> local_ctx = ctx->ptr.Get(iso);
-  Context::Scope context_scope(local_ctx);
+RtnValue ObjectTemplateNewInstance(TemplatePtr ptr, ContextPtr ctx) {
+  LOCAL_TEMPLATE(ptr);
+  TryCatch try_catch(iso);
+  Local<Context> local_ctx = ctx->ptr.Get(iso);
+  Context::Scope context_scope(local_ctx);
+
+  RtnValue rtn = {};
+
+  Local<ObjectTemplate> obj_tmpl = tmpl.As<ObjectTemplate>();
+  Local<Object> obj;
+  if (!obj_tmpl->NewInstance(local_ctx).ToLocal(&obj)) {
+    rtn.error = ExceptionError(try_catch, iso, local_ctx);
+    return rtn;
+  }
+
+  m_value* val = new m_value;
+  val->id = 0;
+  val->iso = iso;
+  val->ctx = ctx;
+  val->ptr = Persistent<Value, CopyablePersistentTraits<Value>>(iso, obj);
+  rtn.value = tracked_value(ctx, val);
+  return rtn;
+}
+
+void ObjectTemplateSetInternalFieldCount(TemplatePtr ptr, int field_count) {
+  LOCAL_TEMPLATE(ptr);
+
+  Local<ObjectTemplate> obj_tmpl = tmpl.As<ObjectTemplate>();
+  obj_tmpl->SetInternalFieldCount(field_count);
+}
+
+int ObjectTemplateInternalFieldCount(TemplatePtr ptr) {
+  LOCAL_TEMPLATE(ptr);
+
+  Local<ObjectTemplate> obj_tmpl = tmpl.As<ObjectTemplate>();
+  return obj_tmpl->InternalFieldCount();
+}
+
+void ObjectTemplateSetAccessor(TemplatePtr ptr,
+                               const char* name,
+                               int callback_ref,
+                               int has_setter,
+                               int attributes) {
+  LOCAL_TEMPLATE(ptr);
+
+  Local<ObjectTemplate> obj_tmpl = tmpl.As<ObjectTemplate>();
+  Local<String> prop_name =
+      String::NewFromUtf8(iso, name, NewStringType::kNormal).ToLocalChecked();
+  obj_tmpl->SetAccessor(prop_name, PropertyAccessorGetter,
+                        has_setter ? PropertyAccessorSetter : nullptr,
+                        Integer::New(iso, callback_ref), DEFAULT,
+                        (PropertyAttribute)attributes);
+}
+
+/********** Accessors **********/
+
+static ValuePtr trackedLocalValue(Isolate* iso,
+                                  m_ctx* ctx,
+                                  Local<Value> local) {
+  m_value* val = new m_value;
+  val->id = 0;
+  val->iso = iso;
+  val->ctx = ctx;
+  val->ptr.Reset(
+      iso, Persistent<Value, CopyablePersistentTraits<Value>>(iso, local));
+  return tracked_value(ctx, val);
+}
+
+static void PropertyAccessorGetter(Local<Name> property,
+                                   const PropertyCallbackInfo<Value>& info) {
+  Isolate* iso = info.GetIsolate();
+  ISOLATE_SCOPE(iso);
+
+  Local<Context> local_ctx = iso->GetCurrentContext();
+  int ctx_ref = local_ctx->GetEmbedderData(1).As<Integer>()->Value();
+  m_ctx* ctx = goContext(ctx_ref);
+
+  int callback_ref = info.Data().As<Integer>()->Value();
+  ValuePtr _this = trackedLocalValue(iso, ctx, info.This());
+  String::Utf8Value name(iso, property);
+
+  ValuePtr val = goAccessorGetterCallback(ctx_ref, callback_ref, _this, *name);
+  if (val != nullptr) {
+    info.GetReturnValue().Set(val->ptr.Get(iso));
+  }
+}
+
+static void PropertyAccessorSetter(Local<Name> property,
+                                   Local<Value> value,
+                                   const PropertyCallbackInfo<void>& info) {
+  Isolate* iso = info.GetIsolate();
+  ISOLATE_SCOPE(iso);
+
+  Local<Context> local_ctx = iso->GetCurrentContext();
+  int ctx_ref = local_ctx->GetEmbedderData(1).As<Integer>()->Value();
+  m_ctx* ctx = goContext(ctx_ref);
+
+  int callback_ref = info.Data().As<Integer>()->Value();
+  ValuePtr _this = trackedLocalValue(iso, ctx, info.This());
+  ValuePtr val = trackedLocalValue(iso, ctx, value);
+  String::Utf8Value name(iso, property);
+
+  goAccessorSetterCallback(ctx_ref, callback_ref, _this, *name, val);
+}
+
+/********** Console **********/
+
+// GoConsoleDelegate passes the calls of the console methods to Go, with their
+// arguments and the stack frame of the caller.
+class GoConsoleDelegate : public debug::ConsoleDelegate {
+ public:
+  void Debug(const debug::ConsoleCallArguments& args,
+             const debug::ConsoleContext&) override {
+    Send(CONSOLE_DEBUG, args);
+  }
+  void Error(const debug::ConsoleCallArguments& args,
+             const debug::ConsoleContext&) override {
+    Send(CONSOLE_ERROR, args);
+  }
+  void Info(const debug::ConsoleCallArguments& args,
+            const debug::ConsoleContext&) override {
+    Send(CONSOLE_INFO, args);
+  }
+  void Log(const debug::ConsoleCallArguments& args,
+           const debug::ConsoleContext&) override {
+    Send(CONSOLE_LOG, args);
+  }
+  void Warn(const debug::ConsoleCallArguments& args,
+            const debug::ConsoleContext&) override {
+    Send(CONSOLE_WARN, args);
+  }
+  void Dir(const debug::ConsoleCallArguments& args,
+           const debug::ConsoleContext&) override {
+    Send(CONSOLE_DIR, args);
+  }
+  void DirXml(const debug::ConsoleCallArguments& args,
+              const debug::ConsoleContext&) override {
+    Send(CONSOLE_DIR_XML, args);
+  }
+  void Table(const debug::ConsoleCallArguments& args,
+             const debug::ConsoleContext&) override {
+    Send(CONSOLE_TABLE, args);
+  }
+  void Trace(const debug::ConsoleCallArguments& args,
+             const debug::ConsoleContext&) override {
+    Send(CONSOLE_TRACE, args);
+  }
+  void Group(const debug::ConsoleCallArguments& args,
+             const debug::ConsoleContext&) override {
+    Send(CONSOLE_GROUP, args);
+  }
+  void GroupCollapsed(const debug::ConsoleCallArguments& args,
+                      const debug::ConsoleContext&) override {
+    Send(CONSOLE_GROUP_COLLAPSED, args);
+  }
+  void GroupEnd(const debug::ConsoleCallArguments& args,
+                const debug::ConsoleContext&) override {
+    Send(CONSOLE_GROUP_END, args);
+  }
+  void Clear(const debug::ConsoleCallArguments& args,
+             const debug::ConsoleContext&) override {
+    Send(CONSOLE_CLEAR, args);
+  }
+  void Count(const debug::ConsoleCallArguments& args,
+             const debug::ConsoleContext&) override {
+    Send(CONSOLE_COUNT, args);
+  }
+  void CountReset(const debug::ConsoleCallArguments& args,
+                  const debug::ConsoleContext&) override {
+    Send(CONSOLE_COUNT_RESET, args);
+  }
+  void Assert(const debug::ConsoleCallArguments& args,
+              const debug::ConsoleContext&) override {
+    Send(CONSOLE_ASSERT, args);
+  }
+  void Profile(const debug::ConsoleCallArguments& args,
+               const debug::ConsoleContext&) override {
+    Send(CONSOLE_PROFILE, args);
+  }
+  void ProfileEnd(const debug::ConsoleCallArguments& args,
+                  const debug::ConsoleContext&) override {
+    Send(CONSOLE_PROFILE_END, args);
+  }
+  void Time(const debug::ConsoleCallArguments& args,
+            const debug::ConsoleContext&) override {
+    Send(CONSOLE_TIME, args);
+  }
+  void TimeLog(const debug::ConsoleCallArguments& args,
+               const debug::ConsoleContext&) override {
+    Send(CONSOLE_TIME_LOG, args);
+  }
+  void TimeEnd(const debug::ConsoleCallArguments& args,
+               const debug::ConsoleContext&) override {
+    Send(CONSOLE_TIME_END, args);
+  }
+  void TimeStamp(const debug::ConsoleCallArguments& args,
+                 const debug::ConsoleContext&) override {
+    Send(CONSOLE_TIME_STAMP, args);
+  }
+
+ private:
+  static void Send(ConsoleMethod method,
+                   const debug::ConsoleCallArguments& args) {
+    Isolate* iso = Isolate::GetCurrent();
+    ISOLATE_SCOPE(iso);
+
+    Local<Context> local_ctx = iso->GetCurrentContext();
+    int ctx_ref = local_ctx->GetEmbedderData(1).As<Integer>()->Value();
+    m_ctx* ctx = goContext(ctx_ref);
+
+    int args_count = args.Length();
+    ValuePtr* vals = new ValuePtr[args_count];
+    for (int i = 0; i < args_count; i++) {
+      vals[i] = trackedLocalValue(iso, ctx, args[i]);
+    }
+
+    // The console methods are builtins without a frame of their own, so the
+    // top frame is the one of their caller, if it is JavaScript.
+    ErrorStackFrame* frame = nullptr;
+    Local<StackTrace> trace =
+        StackTrace::CurrentStackTrace(iso, 1, StackTrace::kDetailed);
+    if (trace->GetFrameCount() > 0) {
+      Local<StackFrame> f = trace->GetFrame(iso, 0);
+      String::Utf8Value script_name(iso, f->GetScriptNameOrSourceURL());
+      String::Utf8Value function_name(iso, f->GetFunctionName());
+      frame = new ErrorStackFrame[1]{{
+          CopyString(script_name),
+          CopyString(function_name),
+          f->GetLineNumber(),
+          f->GetColumn(),
+          f->GetScriptId(),
+          f->IsEval(),
+          f->IsConstructor(),
+          f->IsWasm(),
+      }};
+    }
+
+    goConsoleMessage(ctx_ref, method, vals, args_count, frame);
+
+    delete[] vals;
+    if (frame != nullptr) {
+      ErrorStackFramesDelete(frame, 1);
+    }
+  }
+};
+
+void IsolateSetConsoleDelegate(IsolatePtr iso, int enabled) {
+  isolate_data* iso_data = isolateData(iso);
+  if (enabled && iso_data->console_delegate == nullptr) {
+    iso_data->console_delegate = new GoConsoleDelegate;
+    debug::SetConsoleDelegate(iso, iso_data->console_delegate);
+  } else if (!enabled && iso_data->console_delegate != nullptr) {
+    debug::SetConsoleDelegate(iso, nullptr);
+    delete iso_data->console_delegate;
+    iso_data->console_delegate = nullptr;
+  }
+}
+
+/********** Interceptors **********/
+
+// The ctx_ref, callback_ref and _this arguments of the Go interceptor
+// callbacks, derived from the PropertyCallbackInfo.
+#define INTERCEPTOR_SCOPE(info)                                           \
+  Isolate* iso = info.GetIsolate();                                       \
+  ISOLATE_SCOPE(iso);                                                     \
+  Local<Context> local_ctx = iso->GetCurrentContext();                    \
+  int ctx_ref = local_ctx->GetEmbedderData(1).As<Integer>()->Value();     \
+  m_ctx* ctx = goContext(ctx_ref);                                        \
+  int callback_ref = info.Data().As<Integer>()->Value();                  \
+  ValuePtr _this = trackedLocalValue(iso, ctx, info.This());
+
+static void InterceptNamedGetter(Local<Name> property,
+                                 const PropertyCallbackInfo<Value>& info) {
+  INTERCEPTOR_SCOPE(info);
+  String::Utf8Value name(iso, property);
+
+  ValuePtr val =
+      goPropertyHandlerGetter(ctx_ref, callback_ref, _this, *name, 0);
+  if (val != nullptr) {
+    info.GetReturnValue().Set(val->ptr.Get(iso));
+  }
+}
+
+static void InterceptNamedSetter(Local<Name> property,
+                                 Local<Value> value,
+                                 const PropertyCallbackInfo<Value>& info) {
+  INTERCEPTOR_SCOPE(info);
+  String::Utf8Value name(iso, property);
+  ValuePtr val = trackedLocalValue(iso, ctx, value);
+
+  if (goPropertyHandlerSetter(ctx_ref, callback_ref, _this, *name, 0, val)) {
+    info.GetReturnValue().Set(value);
+  }
+}
+
+static void InterceptNamedQuery(Local<Name> property,
+                                const PropertyCallbackInfo<Integer>& info) {
+  INTERCEPTOR_SCOPE(info);
+  String::Utf8Value name(iso, property);
+
+  int attributes =
+      goPropertyHandlerQuery(ctx_ref, callback_ref, _this, *name, 0);
+  if (attributes >= 0) {
+    info.GetReturnValue().Set(attributes);
+  }
+}
+
+static void InterceptNamedDeleter(Local<Name> property,
+                                  const PropertyCallbackInfo<Boolean>& info) {
+  INTERCEPTOR_SCOPE(info);
+  String::Utf8Value name(iso, property);
+
+  if (goPropertyHandlerDeleter(ctx_ref, callback_ref, _this, *name, 0)) {
+    info.GetReturnValue().Set(true);
+  }
+}
+
+static void InterceptNamedEnumerator(const PropertyCallbackInfo<Array>& info) {
+  INTERCEPTOR_SCOPE(info);
+
+  auto rtn = goNamedPropertyEnumerator(ctx_ref, callback_ref, _this);
+  char** keys = rtn.r0;
+  int count = rtn.r1;
+  Local<Array> arr = Array::New(iso, count);
+  for (int i = 0; i < count; i++) {
+    Local<String> key =
+        String::NewFromUtf8(iso, keys[i], NewStringType::kNormal)
+            .ToLocalChecked();
+    arr->Set(local_ctx, i, key).Check();
+    free(keys[i]);
+  }
+  free(keys);
+  info.GetReturnValue().Set(arr);
+}
+
+static void InterceptIndexedGetter(uint32_t index,
+                                   const PropertyCallbackInfo<Value>& info) {
+  INTERCEPTOR_SCOPE(info);
+
+  ValuePtr val =
+      goPropertyHandlerGetter(ctx_ref, callback_ref, _this, nullptr, index);
+  if (val != nullptr) {
+    info.GetReturnValue().Set(val->ptr.Get(iso));
+  }
+}
+
+static void InterceptIndexedSetter(uint32_t index,
+                                   Local<Value> value,
+                                   const PropertyCallbackInfo<Value>& info) {
+  INTERCEPTOR_SCOPE(info);
+  ValuePtr val = trackedLocalValue(iso, ctx, value);
+
+  if (goPropertyHandlerSetter(ctx_ref, callback_ref, _this, nullptr, index,
+                              val)) {
+    info.GetReturnValue().Set(value);
+  }
+}
+
+static void InterceptIndexedQuery(uint32_t index,
+                                  const PropertyCallbackInfo<Integer>& info) {
+  INTERCEPTOR_SCOPE(info);
 
-  RtnValue rtn = {};
+  int attributes =
+      goPropertyHandlerQuery(ctx_ref, callback_ref, _this, nullptr, index);
+  if (attributes >= 0) {
+    info.GetReturnValue().Set(attributes);
+  }
+}
 
-  Local<ObjectTemplate> obj_tmpl = tmpl.As<ObjectTemplate>();
-  Local<Object> obj;
-  if (!obj_tmpl->NewInstance(local_ctx).ToLocal(&obj)) {
-    rtn.error = ExceptionError(try_catch, iso, local_ctx);
-    return rtn;
+static void InterceptIndexedDeleter(uint32_t index,
+                                    const PropertyCallbackInfo<Boolean>& info) {
+  INTERCEPTOR_SCOPE(info);
+
+  if (goPropertyHandlerDeleter(ctx_ref, callback_ref, _this, nullptr, index)) {
+    info.GetReturnValue().Set(true);
   }
+}
 
-  m_value* val = new m_value;
-  val->id = 0;
-  val->iso = iso;
-  val->ctx = ctx;
-  val->ptr = Persistent<Value, CopyablePersistentTraits<Value>>(iso, obj);
-  rtn.value = tracked_value(ctx, val);
-  return rtn;
+static void InterceptIndexedEnumerator(
+    const PropertyCallbackInfo<Array>& info) {
+  INTERCEPTOR_SCOPE(info);
+
+  auto rtn = goIndexedPropertyEnumerator(ctx_ref, callback_ref, _this);
+  uint32_t* indices = rtn.r0;
+  int count = rtn.r1;
+  Local<Array> arr = Array::New(iso, count);
+  for (int i = 0; i < count; i++) {
+    arr->Set(local_ctx, i, Integer::NewFromUnsigned(iso, indices[i])).Check();
+  }
+  free(indices);
+  info.GetReturnValue().Set(arr);
 }
 
-void ObjectTemplateSetInternalFieldCount(TemplatePtr ptr, int field_count) {
+void ObjectTemplateSetNamedPropertyHandler(TemplatePtr ptr,
+                                           int callback_ref,
+                                           int callbacks) {
   LOCAL_TEMPLATE(ptr);
 
   Local<ObjectTemplate> obj_tmpl = tmpl.As<ObjectTemplate>();
-  obj_tmpl->SetInternalFieldCount(field_count);
-}
-
-int ObjectTemplateInternalFieldCount(TemplatePtr ptr) {
+  obj_tmpl->SetHandler(NamedPropertyHandlerConfiguration(
+      InterceptNamedGetter,
+      callbacks & PropertyHandlerSetter ? InterceptNamedSetter : nullptr,
+      callbacks & PropertyHandlerQuery ? InterceptNamedQuery : nullptr,
+      callbacks & PropertyHandlerDeleter ? InterceptNamedDeleter : nullptr,
+      callbacks & PropertyHandlerEnumerator ? InterceptNamedEnumerator
+                                            : nullptr,
+      Integer::New(iso, callback_ref),
+      PropertyHandlerFlags::kOnlyInterceptStrings));
+}
+
+void ObjectTemplateSetIndexedPropertyHandler(TemplatePtr ptr,
+                                             int callback_ref,
+                                             int callbacks) {
   LOCAL_TEMPLATE(ptr);
 
   Local<ObjectTemplate> obj_tmpl = tmpl.As<ObjectTemplate>();
-  return obj_tmpl->InternalFieldCount();
+  obj_tmpl->SetHandler(IndexedPropertyHandlerConfiguration(
+      InterceptIndexedGetter,
+      callbacks & PropertyHandlerSetter ? InterceptIndexedSetter : nullptr,
+      callbacks & PropertyHandlerQuery ? InterceptIndexedQuery : nullptr,
+      callbacks & PropertyHandlerDeleter ? InterceptIndexedDeleter : nullptr,
+      callbacks & PropertyHandlerEnumerator ? InterceptIndexedEnumerator
+                                            : nullptr,
+      Integer::New(iso, callback_ref)));
 }
 
 /********** FunctionTemplate **********/
@@ -498,10 +1753,17 @@ static void FunctionTemplateCallback(const FunctionCallbackInfo<Value>& info) {
   _this->ptr.Reset(iso, Persistent<Value, CopyablePersistentTraits<Value>>(
                             iso, info.This()));
 
+  // new.target is only set for construct calls, eg. `new Foo()`
+  ValuePtr new_target = nullptr;
+  if (!info.NewTarget()->IsUndefined()) {
+    new_target = trackedLocalValue(iso, ctx, info.NewTarget());
+  }
+
   int args_count = info.Length();
-  ValuePtr thisAndArgs[args_count + 1];
+  ValuePtr thisAndArgs[args_count + 2];
   thisAndArgs[0] = tracked_value(ctx, _this);
-  ValuePtr* args = thisAndArgs + 1;
+  thisAndArgs[1] = new_target;
+  ValuePtr* args = thisAndArgs + 2;
   for (int i = 0; i < args_count; i++) {
     m_value* val = new m_value;
     val->id = 0;
@@ -539,6 +1801,44 @@ TemplatePtr NewFunctionTemplate(IsolatePtr iso, int callback_ref) {
   return ot;
 }
 
+TemplatePtr FunctionTemplateInstanceTemplate(TemplatePtr ptr) {
+  LOCAL_TEMPLATE(ptr);
+
+  Local<FunctionTemplate> fn_tmpl = tmpl.As<FunctionTemplate>();
+  m_template* ot = new m_template;
+  ot->iso = iso;
+  ot->ptr.Reset(iso, fn_tmpl->InstanceTemplate());
+  return ot;
+}
+
+TemplatePtr FunctionTemplatePrototypeTemplate(TemplatePtr ptr) {
+  LOCAL_TEMPLATE(ptr);
+
+  Local<FunctionTemplate> fn_tmpl = tmpl.As<FunctionTemplate>();
+  m_template* ot = new m_template;
+  ot->iso = iso;
+  ot->ptr.Reset(iso, fn_tmpl->PrototypeTemplate());
+  return ot;
+}
+
+void FunctionTemplateInherit(TemplatePtr ptr, TemplatePtr parent_ptr) {
+  LOCAL_TEMPLATE(ptr);
+
+  Local<FunctionTemplate> fn_tmpl = tmpl.As<FunctionTemplate>();
+  Local<FunctionTemplate> parent =
+      parent_ptr->ptr.Get(iso).As<FunctionTemplate>();
+  fn_tmpl->Inherit(parent);
+}
+
+void FunctionTemplateSetClassName(TemplatePtr ptr, const char* name) {
+  LOCAL_TEMPLATE(ptr);
+
+  Local<FunctionTemplate> fn_tmpl = tmpl.As<FunctionTemplate>();
+  Local<String> class_name =
+      String::NewFromUtf8(iso, name, NewStringType::kNormal).ToLocalChecked();
+  fn_tmpl->SetClassName(class_name);
+}
+
 RtnValue FunctionTemplateGetFunction(TemplatePtr ptr, ContextPtr ctx) {
   LOCAL_TEMPLATE(ptr);
   TryCatch try_catch(iso);
@@ -601,6 +1901,25 @@ ContextPtr NewContext(IsolatePtr iso,
   return ctx;
 }
 
+ContextPtr NewContextFromSnapshot(IsolatePtr iso, size_t index, int ref) {
+  Locker locker(iso);
+  Isolate::Scope isolate_scope(iso);
+  HandleScope handle_scope(iso);
+
+  Local<Context> local_ctx;
+  if (!Context::FromSnapshot(iso, index).ToLocal(&local_ctx)) {
+    return nullptr;
+  }
+  // The embedder data is restored from the snapshot, so it refers to the
+  // context the snapshot was created from.
+  local_ctx->SetEmbedderData(1, Integer::New(iso, ref));
+
+  m_ctx* ctx = new m_ctx;
+  ctx->ptr.Reset(iso, local_ctx);
+  ctx->iso = iso;
+  return ctx;
+}
+
 int ContextRetainedValueCount(ContextPtr ctx) {
   return ctx->vals.size();
 }
@@ -794,6 +2113,430 @@ ValuePtr ContextGlobal(ContextPtr ctx) {
   return tracked_value(ctx, val);
 }
 
+/********** Module **********/
+
+static m_module* lookupModule(Isolate* iso, Local<Module> module) {
+  auto range = isolateData(iso)->modules.equal_range(module->GetIdentityHash());
+  for (auto it = range.first; it != range.second; ++it) {
+    if (it->second->ptr == module) {
+      return it->second;
+    }
+  }
+  return nullptr;
+}
+
+RtnModule IsolateCompileModule(IsolatePtr iso, const char* s, const char* o) {
+  ISOLATE_SCOPE_INTERNAL_CONTEXT(iso);
+  TryCatch try_catch(iso);
+  Local<Context> local_ctx = ctx->ptr.Get(iso);
+  Context::Scope context_scope(local_ctx);
+
+  RtnModule rtn = {};
+
+  Local<String> src, ogn;
+  if (!String::NewFromUtf8(iso, s, NewStringType::kNormal).ToLocal(&src) ||
+      !String::NewFromUtf8(iso, o, NewStringType::kNormal).ToLocal(&ogn)) {
+    rtn.error = ExceptionError(try_catch, iso, local_ctx);
+    return rtn;
+  }
+
+  ScriptOrigin script_origin(iso, ogn, 0, 0, false, -1, Local<Value>(), false,
+                             false, true);
+  ScriptCompiler::Source source(src, script_origin);
+
+  Local<Module> module;
+  if (!ScriptCompiler::CompileModule(iso, &source).ToLocal(&module)) {
+    rtn.error = ExceptionError(try_catch, iso, local_ctx);
+    return rtn;
+  }
+
+  m_module* mod = new m_module;
+  mod->iso = iso;
+  mod->ptr.Reset(iso, module);
+  isolateData(iso)->modules.emplace(module->GetIdentityHash(), mod);
+  rtn.ptr = mod;
+  return rtn;
+}
+
+static MaybeLocal<Module> ResolveModuleCallback(
+    Local<Context> context,
+    Local<String> specifier,
+    Local<FixedArray> import_assertions,
+    Local<Module> referrer) {
+  Isolate* iso = context->GetIsolate();
+  int ctx_ref = context->GetEmbedderData(1).As<Integer>()->Value();
+
+  String::Utf8Value spec(iso, specifier);
+  m_module* referrer_mod = lookupModule(iso, referrer);
+
+  auto rtn = goResolveModuleCallback(ctx_ref, *spec, referrer_mod);
+  if (rtn.r1 != nullptr) {
+    Local<String> msg =
+        String::NewFromUtf8(iso, rtn.r1, NewStringType::kNormal)
+            .ToLocalChecked();
+    free(rtn.r1);
+    iso->ThrowException(Exception::Error(msg));
+    return MaybeLocal<Module>();
+  }
+  return rtn.r0->ptr.Get(iso);
+}
+
+RtnBool ModuleInstantiate(ModulePtr ptr, ContextPtr ctx) {
+  LOCAL_CONTEXT(ctx);
+
+  RtnBool rtn = {};
+  Local<Module> module = ptr->ptr.Get(iso);
+  if (module->InstantiateModule(local_ctx, ResolveModuleCallback).IsNothing()) {
+    rtn.error = ExceptionError(try_catch, iso, local_ctx);
+    return rtn;
+  }
+  rtn.value = 1;
+  return rtn;
+}
+
+RtnValue ModuleEvaluate(ModulePtr ptr, ContextPtr ctx) {
+  LOCAL_CONTEXT(ctx);
+
+  RtnValue rtn = {};
+  Local<Module> module = ptr->ptr.Get(iso);
+  Local<Value> result;
+  if (!module->Evaluate(local_ctx).ToLocal(&result)) {
+    rtn.error = ExceptionError(try_catch, iso, local_ctx);
+    return rtn;
+  }
+  m_value* val = new m_value;
+  val->id = 0;
+  val->iso = iso;
+  val->ctx = ctx;
+  val->ptr = Persistent<Value, CopyablePersistentTraits<Value>>(iso, result);
+  rtn.value = tracked_value(ctx, val);
+  return rtn;
+}
+
+int ModuleGetStatus(ModulePtr ptr) {
+  Isolate* iso = ptr->iso;
+  ISOLATE_SCOPE(iso);
+  return ptr->ptr.Get(iso)->GetStatus();
+}
+
+ValuePtr ModuleGetNamespace(ModulePtr ptr, ContextPtr ctx) {
+  LOCAL_CONTEXT(ctx);
+
+  Local<Module> module = ptr->ptr.Get(iso);
+  m_value* val = new m_value;
+  val->id = 0;
+  val->iso = iso;
+  val->ctx = ctx;
+  val->ptr = Persistent<Value, CopyablePersistentTraits<Value>>(
+      iso, module->GetModuleNamespace());
+  return tracked_value(ctx, val);
+}
+
+static MaybeLocal<Promise> ImportModuleDynamicallyCallback(
+    Local<Context> context,
+    Local<Data> host_defined_options,
+    Local<Value> resource_name,
+    Local<String> specifier,
+    Local<FixedArray> import_assertions) {
+  Isolate* iso = context->GetIsolate();
+  int ctx_ref = context->GetEmbedderData(1).As<Integer>()->Value();
+
+  String::Utf8Value spec(iso, specifier);
+  String::Utf8Value referrer(iso, resource_name);
+
+  ValuePtr promise = goDynamicImportCallback(ctx_ref, *spec, *referrer);
+  return promise->ptr.Get(iso).As<Promise>();
+}
+
+void IsolateSetHostImportModuleDynamicallyCallback(IsolatePtr iso) {
+  iso->SetHostImportModuleDynamicallyCallback(ImportModuleDynamicallyCallback);
+}
+
+static void InitializeImportMetaObjectCallback(Local<Context> context,
+                                               Local<Module> module,
+                                               Local<Object> meta) {
+  Isolate* iso = context->GetIsolate();
+  int ctx_ref = context->GetEmbedderData(1).As<Integer>()->Value();
+  m_ctx* ctx = goContext(ctx_ref);
+
+  m_value* val = new m_value;
+  val->id = 0;
+  val->iso = iso;
+  val->ctx = ctx;
+  val->ptr = Persistent<Value, CopyablePersistentTraits<Value>>(iso, meta);
+
+  goImportMetaCallback(ctx_ref, lookupModule(iso, module),
+                       tracked_value(ctx, val));
+}
+
+void IsolateSetHostInitializeImportMetaObjectCallback(IsolatePtr iso) {
+  iso->SetHostInitializeImportMetaObjectCallback(
+      InitializeImportMetaObjectCallback);
+}
+
+/********** SnapshotCreator **********/
+
+SnapshotCreatorPtr NewSnapshotCreator(int ref) {
+  SnapshotCreator* creator = new SnapshotCreator(external_references);
+  Isolate* iso = creator->GetIsolate();
+
+  // The creator enters the isolate on the current thread, but as with any other
+  // isolate we enter it for each call instead; it is entered again when the
+  // creator is deleted, which exits the isolate.
+  iso->Exit();
+  InitIsolate(iso, ref);
+
+  m_snapshotCreator* sc = new m_snapshotCreator;
+  sc->ptr = creator;
+  sc->iso = iso;
+  sc->default_context_set = false;
+  return sc;
+}
+
+IsolatePtr SnapshotCreatorGetIsolate(SnapshotCreatorPtr sc) {
+  return sc->iso;
+}
+
+void SnapshotCreatorSetDefaultContext(SnapshotCreatorPtr sc, ContextPtr ctx) {
+  Isolate* iso = sc->iso;
+  ISOLATE_SCOPE(iso);
+  sc->ptr->SetDefaultContext(ctx->ptr.Get(iso));
+  sc->default_context_set = true;
+}
+
+size_t SnapshotCreatorAddContext(SnapshotCreatorPtr sc, ContextPtr ctx) {
+  Isolate* iso = sc->iso;
+  ISOLATE_SCOPE(iso);
+  return sc->ptr->AddContext(ctx->ptr.Get(iso));
+}
+
+SnapshotBlob SnapshotCreatorCreateBlob(SnapshotCreatorPtr sc,
+                                       int function_code_handling) {
+  Isolate* iso = sc->iso;
+  StartupData blob = {nullptr, 0};
+  {
+    Locker locker(iso);
+    iso->Enter();
+
+    if (!sc->default_context_set) {
+      HandleScope handle_scope(iso);
+      sc->ptr->SetDefaultContext(Context::New(iso));
+    }
+    IsolateFreeData(iso);
+
+    blob = sc->ptr->CreateBlob(
+        static_cast<SnapshotCreator::FunctionCodeHandling>(
+            function_code_handling));
+  }
+  // Deleting the creator exits and disposes the isolate, so it must be
+  // unlocked by then.
+  delete sc->ptr;
+  delete sc;
+  return SnapshotBlob{blob.data, blob.raw_size};
+}
+
+void SnapshotCreatorDispose(SnapshotCreatorPtr sc) {
+  Isolate* iso = sc->iso;
+  {
+    Locker locker(iso);
+    iso->Enter();
+    IsolateFreeData(iso);
+  }
+  delete sc->ptr;
+  delete sc;
+}
+
+void SnapshotBlobDelete(SnapshotBlob blob) {
+  delete[] blob.data;
+}
+
+/********** Inspector **********/
+
+// All contexts of an isolate are in the same context group of its inspector.
+static const int kInspectorContextGroupId = 1;
+
+class GoInspectorClient : public v8_inspector::V8InspectorClient {
+ public:
+  explicit GoInspectorClient(Isolate* iso) : iso_(iso) {}
+
+  void runMessageLoopOnPause(int contextGroupId) override {
+    goInspectorRunMessageLoopOnPause(isolateData(iso_)->ref);
+  }
+  void quitMessageLoopOnPause() override {
+    goInspectorQuitMessageLoopOnPause(isolateData(iso_)->ref);
+  }
+  void runIfWaitingForDebugger(int contextGroupId) override {
+    goInspectorRunIfWaitingForDebugger(isolateData(iso_)->ref);
+  }
+  double currentTimeMS() override {
+    return std::chrono::duration<double, std::milli>(
+               std::chrono::system_clock::now().time_since_epoch())
+        .count();
+  }
+  // The context of evaluations that don't specify one, such as from the
+  // DevTools console.
+  Local<Context> ensureDefaultContextInGroup(int contextGroupId) override {
+    return default_context.Get(iso_);
+  }
+
+  Global<Context> default_context;
+
+ private:
+  Isolate* iso_;
+};
+
+// InspectorChannel passes the responses and notifications of a session to Go.
+class InspectorChannel : public v8_inspector::V8Inspector::Channel {
+ public:
+  InspectorChannel(int iso_ref, int session_ref)
+      : iso_ref_(iso_ref), session_ref_(session_ref) {}
+
+  void sendResponse(
+      int callId,
+      std::unique_ptr<v8_inspector::StringBuffer> message) override {
+    Send(message->string());
+  }
+  void sendNotification(
+      std::unique_ptr<v8_inspector::StringBuffer> message) override {
+    Send(message->string());
+  }
+  void flushProtocolNotifications() override {}
+
+ private:
+  // The 8-bit messages of V8 are encoded in UTF-8, the others in UTF-16.
+  void Send(const v8_inspector::StringView& msg) {
+    if (msg.is8Bit()) {
+      goInspectorSessionSend(iso_ref_, session_ref_,
+                             (char*)msg.characters8(), nullptr,
+                             msg.length());
+    } else {
+      goInspectorSessionSend(iso_ref_, session_ref_, nullptr,
+                             (uint16_t*)msg.characters16(), msg.length());
+    }
+  }
+
+  int iso_ref_;
+  int session_ref_;
+};
+
+struct m_inspector {
+  Isolate* iso;
+  GoInspectorClient* client;
+  std::unique_ptr<v8_inspector::V8Inspector> inspector;
+};
+
+struct m_inspectorSession {
+  Isolate* iso;
+  InspectorChannel* channel;
+  std::unique_ptr<v8_inspector::V8InspectorSession> session;
+};
+
+InspectorPtr NewInspector(IsolatePtr iso) {
+  ISOLATE_SCOPE(iso);
+
+  m_inspector* ins = new m_inspector;
+  ins->iso = iso;
+  ins->client = new GoInspectorClient(iso);
+  // The inspector reports the console messages of scripts to its sessions,
+  // replacing any console delegate of the isolate.
+  ins->inspector = v8_inspector::V8Inspector::create(iso, ins->client);
+  isolate_data* iso_data = isolateData(iso);
+  if (iso_data->console_delegate != nullptr) {
+    delete iso_data->console_delegate;
+    iso_data->console_delegate = nullptr;
+  }
+  return ins;
+}
+
+void InspectorDelete(InspectorPtr ptr) {
+  Isolate* iso = ptr->iso;
+  ISOLATE_SCOPE(iso);
+
+  // The inspector unsets the console delegate of the isolate when deleted,
+  // even if it was replaced by IsolateSetConsoleDelegate.
+  ptr->inspector.reset();
+  isolate_data* iso_data = isolateData(iso);
+  if (iso_data->console_delegate != nullptr) {
+    debug::SetConsoleDelegate(iso, iso_data->console_delegate);
+  }
+  ptr->client->default_context.Reset();
+  delete ptr->client;
+  delete ptr;
+}
+
+void InspectorContextCreated(InspectorPtr ptr,
+                             ContextPtr ctx_ptr,
+                             const char* name) {
+  Isolate* iso = ptr->iso;
+  ISOLATE_SCOPE(iso);
+
+  Local<Context> ctx = ctx_ptr->ptr.Get(iso);
+  v8_inspector::StringView name_view((const uint8_t*)name, strlen(name));
+  ptr->inspector->contextCreated(
+      v8_inspector::V8ContextInfo(ctx, kInspectorContextGroupId, name_view));
+  if (ptr->client->default_context.IsEmpty()) {
+    ptr->client->default_context.Reset(iso, ctx);
+  }
+}
+
+void InspectorContextDestroyed(InspectorPtr ptr, ContextPtr ctx_ptr) {
+  Isolate* iso = ptr->iso;
+  ISOLATE_SCOPE(iso);
+
+  Local<Context> ctx = ctx_ptr->ptr.Get(iso);
+  ptr->inspector->contextDestroyed(ctx);
+  if (ptr->client->default_context == ctx) {
+    ptr->client->default_context.Reset();
+  }
+}
+
+static void InspectorInterrupt(Isolate* iso, void* data) {
+  goInspectorRunPending(isolateData(iso)->ref, 0);
+}
+
+void InspectorRequestInterrupt(InspectorPtr ptr) {
+  ptr->iso->RequestInterrupt(InspectorInterrupt, nullptr);
+}
+
+void InspectorRunPending(InspectorPtr ptr) {
+  Isolate* iso = ptr->iso;
+  ISOLATE_SCOPE(iso);
+
+  goInspectorRunPending(isolateData(iso)->ref, 1);
+}
+
+InspectorSessionPtr InspectorConnect(InspectorPtr ptr, int session_ref) {
+  Isolate* iso = ptr->iso;
+  ISOLATE_SCOPE(iso);
+
+  m_inspectorSession* session = new m_inspectorSession;
+  session->iso = iso;
+  session->channel = new InspectorChannel(isolateData(iso)->ref, session_ref);
+  session->session = ptr->inspector->connect(
+      kInspectorContextGroupId, session->channel, v8_inspector::StringView(),
+      v8_inspector::V8Inspector::kFullyTrusted);
+  return session;
+}
+
+void InspectorSessionDispatchMessage(InspectorSessionPtr ptr,
+                                     const uint16_t* message,
+                                     int length) {
+  Isolate* iso = ptr->iso;
+  ISOLATE_SCOPE(iso);
+
+  ptr->session->dispatchProtocolMessage(
+      v8_inspector::StringView(message, length));
+}
+
+void InspectorSessionDelete(InspectorSessionPtr ptr) {
+  Isolate* iso = ptr->iso;
+  ISOLATE_SCOPE(iso);
+
+  ptr->session.reset();
+  delete ptr->channel;
+  delete ptr;
+}
+
 /********** Value **********/
 
 #define LOCAL_VALUE(val)                   \
@@ -1338,6 +3081,28 @@ void ObjectSetIdx(ValuePtr ptr, uint32_t idx, ValuePtr prop_val) {
   obj->Set(local_ctx, idx, prop_val->ptr.Get(iso)).Check();
 }
 
+RtnBool ObjectSetAccessor(ValuePtr ptr,
+                          const char* key,
+                          int callback_ref,
+                          int has_setter,
+                          int attributes) {
+  LOCAL_OBJECT(ptr);
+  RtnBool rtn = {};
+
+  Local<String> key_val =
+      String::NewFromUtf8(iso, key, NewStringType::kNormal).ToLocalChecked();
+  Maybe<bool> set = obj->SetAccessor(
+      local_ctx, key_val, PropertyAccessorGetter,
+      has_setter ? PropertyAccessorSetter : nullptr,
+      Integer::New(iso, callback_ref), DEFAULT, (PropertyAttribute)attributes);
+  if (set.IsNothing()) {
+    rtn.error = ExceptionError(try_catch, iso, local_ctx);
+    return rtn;
+  }
+  rtn.value = set.FromJust();
+  return rtn;
+}
+
 int ObjectSetInternalField(ValuePtr ptr, int idx, ValuePtr val_ptr) {
   LOCAL_OBJECT(ptr);
   m_value* prop_val = static_cast<m_value*>(val_ptr);
@@ -51,16 +51,43 @@ typedef struct m_ctx m_ctx;
 typedef struct m_value m_value;
 typedef struct m_template m_template;
 typedef struct m_unboundScript m_unboundScript;
+typedef struct m_module m_module;
+typedef struct m_snapshotCreator m_snapshotCreator;
+typedef struct m_inspector m_inspector;
+typedef struct m_inspectorSession m_inspectorSession;
 
 typedef m_ctx* ContextPtr;
 typedef m_value* ValuePtr;
 typedef m_template* TemplatePtr;
 typedef m_unboundScript* UnboundScriptPtr;
+typedef m_module* ModulePtr;
+typedef m_snapshotCreator* SnapshotCreatorPtr;
+typedef m_inspector* InspectorPtr;
+typedef m_inspectorSession* InspectorSessionPtr;
+
+typedef struct {
+  const char* scriptName;
+  const char* functionName;
+  int lineNumber;
+  int columnNumber;
+  int scriptId;
+  int isEval;
+  int isConstructor;
+  int isWasm;
+} ErrorStackFrame;
 
 typedef struct {
   const char* msg;
   const char* location;
   const char* stack;
+  int terminated;
+  int heapLimitExceeded;
+  const char* name;
+  ErrorStackFrame* frames;
+  int frameCount;
+  ValuePtr exception;
+  int exceptionCtxRef;
+  int isolateRef;
 } RtnError;
 
 typedef struct {
@@ -69,6 +96,62 @@ typedef struct {
   RtnError error;
 } RtnUnboundScript;
 
+typedef struct {
+  ModulePtr ptr;
+  RtnError error;
+} RtnModule;
+
+typedef struct {
+  int value;
+  RtnError error;
+} RtnBool;
+
+// The kinds of errors created by NewValueError, in the order of ErrorKind in
+// errors.go.
+typedef enum {
+  ERROR_GENERIC = 0,
+  ERROR_RANGE,
+  ERROR_REFERENCE,
+  ERROR_SYNTAX,
+  ERROR_TYPE,
+} ErrorKind;
+
+// The optional callbacks of a property handler, set on an ObjectTemplate;
+// the getter is always set.
+typedef enum {
+  PropertyHandlerSetter = 1 << 0,
+  PropertyHandlerQuery = 1 << 1,
+  PropertyHandlerDeleter = 1 << 2,
+  PropertyHandlerEnumerator = 1 << 3,
+} PropertyHandlerCallbacks;
+
+// The methods of the console object, in the order of ConsoleMethod in
+// console.go.
+typedef enum {
+  CONSOLE_DEBUG = 0,
+  CONSOLE_ERROR,
+  CONSOLE_INFO,
+  CONSOLE_LOG,
+  CONSOLE_WARN,
+  CONSOLE_DIR,
+  CONSOLE_DIR_XML,
+  CONSOLE_TABLE,
+  CONSOLE_TRACE,
+  CONSOLE_GROUP,
+  CONSOLE_GROUP_COLLAPSED,
+  CONSOLE_GROUP_END,
+  CONSOLE_CLEAR,
+  CONSOLE_COUNT,
+  CONSOLE_COUNT_RESET,
+  CONSOLE_ASSERT,
+  CONSOLE_PROFILE,
+  CONSOLE_PROFILE_END,
+  CONSOLE_TIME,
+  CONSOLE_TIME_LOG,
+  CONSOLE_TIME_END,
+  CONSOLE_TIME_STAMP,
+} ConsoleMethod;
+
 typedef struct {
   ScriptCompilerCachedDataPtr ptr;
   const uint8_t* data;
@@ -86,6 +169,18 @@ typedef struct {
   IsolatePtr iso;
 } CPUProfiler;
 
+typedef struct {
+  int line;
+  unsigned hitCount;
+} CPUProfileLineTick;
+
+typedef struct {
+  int mode;
+  unsigned maxSamples;
+  int samplingIntervalUs;
+  ContextPtr filterContext;
+} CPUProfilingOptions;
+
 typedef struct CPUProfileNode {
   CpuProfileNodePtr ptr;
   unsigned nodeId;
@@ -96,6 +191,8 @@ typedef struct CPUProfileNode {
   int columnNumber;
   unsigned hitCount;
   const char* bailoutReason;
+  int lineTicksCount;
+  CPUProfileLineTick* lineTicks;
   int childrenCount;
   struct CPUProfileNode** children;
 } CPUProfileNode;
@@ -106,8 +203,42 @@ typedef struct {
   CPUProfileNode* root;
   int64_t startTime;
   int64_t endTime;
+  int samplesCount;
+  unsigned* sampleNodeIds;
+  int64_t* sampleTimestamps;
 } CPUProfile;
 
+typedef struct {
+  size_t size;
+  unsigned count;
+} AllocationProfileAllocation;
+
+typedef struct AllocationProfileNode {
+  unsigned nodeId;
+  int scriptId;
+  const char* scriptResourceName;
+  const char* functionName;
+  int lineNumber;
+  int columnNumber;
+  int allocationsCount;
+  AllocationProfileAllocation* allocations;
+  int childrenCount;
+  struct AllocationProfileNode** children;
+} AllocationProfileNode;
+
+typedef struct {
+  ValuePtr object;
+  const char* name;
+} HeapSnapshotObjectName;
+
+typedef struct {
+  int exposeNumericValues;
+  int exposeInternals;
+  int nameGlobalObjects;
+  HeapSnapshotObjectName* objectNames;
+  int objectNamesCount;
+} HeapSnapshotOptions;
+
 typedef struct {
   ValuePtr value;
   RtnError error;
@@ -133,6 +264,51 @@ typedef struct {
   size_t number_of_detached_contexts;
 } IsolateHStatistics;
 
+typedef struct {
+  const char* space_name;
+  size_t space_size;
+  size_t space_used_size;
+  size_t space_available_size;
+  size_t physical_space_size;
+} IsolateHeapSpaceStatistics;
+
+typedef struct {
+  size_t code_and_metadata_size;
+  size_t bytecode_and_metadata_size;
+  size_t external_script_source_size;
+  size_t cpu_profiler_metadata_size;
+} IsolateHeapCodeStatistics;
+
+typedef struct {
+  const char* object_type;
+  const char* object_sub_type;
+  size_t object_count;
+  size_t object_size;
+} IsolateHeapObjectStatistics;
+
+// The number of GC types, from kGCTypeScavenge to kGCTypeProcessWeakCallbacks.
+#define GC_TYPE_COUNT 5
+
+typedef struct {
+  uint64_t count[GC_TYPE_COUNT];
+  int64_t total_pause_ns[GC_TYPE_COUNT];
+} IsolateGCStatistics;
+
+typedef struct {
+  size_t initial_heap_size;
+  size_t max_heap_size;
+  size_t max_old_generation_size;
+  size_t max_young_generation_size;
+  size_t initial_old_generation_size;
+  size_t initial_young_generation_size;
+  size_t code_range_size;
+} IsolateConstraints;
+
+typedef struct {
+  const char* data;
+  int raw_size;
+} SnapshotBlob;
+
 typedef struct {
   const uint64_t* word_array;
   int word_count;
@@ -140,14 +316,35 @@ typedef struct {
 } ValueBigInt;
 
 extern void Init();
-extern IsolatePtr NewIsolate();
+extern IsolatePtr NewIsolate(IsolateConstraints* constraints,
+                             const char* snapshot_blob,
+                             int snapshot_blob_size,
+                             int ref);
 extern void IsolatePerformMicrotaskCheckpoint(IsolatePtr ptr);
 extern void IsolateDispose(IsolatePtr ptr);
 extern void IsolateTerminateExecution(IsolatePtr ptr);
+extern void IsolateCancelTerminateExecution(IsolatePtr ptr);
+extern void IsolateSetPrepareStackTraceCallback(IsolatePtr ptr);
+extern void IsolateSetConsoleDelegate(IsolatePtr ptr, int enabled);
 extern int IsolateIsExecutionTerminating(IsolatePtr ptr);
 extern IsolateHStatistics IsolationGetHeapStatistics(IsolatePtr ptr);
-
+extern IsolateGCStatistics IsolateGetGCStatistics(IsolatePtr ptr);
+extern void IsolateSetGCCallbacks(IsolatePtr ptr, int enabled);
+extern size_t IsolateNumberOfHeapSpaces(IsolatePtr ptr);
+extern IsolateHeapSpaceStatistics IsolateGetHeapSpaceStatistics(IsolatePtr ptr,
+                                                                size_t index);
+extern IsolateHeapCodeStatistics IsolateGetHeapCodeStatistics(IsolatePtr ptr);
+extern size_t IsolateNumberOfTrackedHeapObjectTypes(IsolatePtr ptr);
+extern IsolateHeapObjectStatistics IsolateGetHeapObjectStatistics(
+    IsolatePtr ptr,
+    size_t index);
+
+extern void ErrorStackFramesDelete(ErrorStackFrame* frames, int count);
 extern ValuePtr IsolateThrowException(IsolatePtr iso, ValuePtr value);
+extern ValuePtr NewValueError(IsolatePtr iso,
+                              ErrorKind kind,
+                              const char* message,
+                              ValuePtr cause);
 
 extern RtnUnboundScript IsolateCompileUnboundScript(IsolatePtr iso_ptr,
                                                     const char* source,
@@ -160,16 +357,70 @@ extern void ScriptCompilerCachedDataDelete(
     ScriptCompilerCachedData* cached_data);
 extern RtnValue UnboundScriptRun(ContextPtr ctx_ptr, UnboundScriptPtr us_ptr);
 
+extern RtnModule IsolateCompileModule(IsolatePtr iso_ptr,
+                                      const char* source,
+                                      const char* origin);
+extern RtnBool ModuleInstantiate(ModulePtr ptr, ContextPtr ctx_ptr);
+extern RtnValue ModuleEvaluate(ModulePtr ptr, ContextPtr ctx_ptr);
+extern int ModuleGetStatus(ModulePtr ptr);
+extern ValuePtr ModuleGetNamespace(ModulePtr ptr, ContextPtr ctx_ptr);
+extern void IsolateSetHostImportModuleDynamicallyCallback(IsolatePtr iso_ptr);
+extern void IsolateSetHostInitializeImportMetaObjectCallback(
+    IsolatePtr iso_ptr);
+
+extern SnapshotCreatorPtr NewSnapshotCreator(int ref);
+extern IsolatePtr SnapshotCreatorGetIsolate(SnapshotCreatorPtr ptr);
+extern void SnapshotCreatorSetDefaultContext(SnapshotCreatorPtr ptr,
+                                             ContextPtr ctx_ptr);
+extern size_t SnapshotCreatorAddContext(SnapshotCreatorPtr ptr,
+                                        ContextPtr ctx_ptr);
+extern SnapshotBlob SnapshotCreatorCreateBlob(SnapshotCreatorPtr ptr,
+                                              int function_code_handling);
+extern void SnapshotCreatorDispose(SnapshotCreatorPtr ptr);
+extern void SnapshotBlobDelete(SnapshotBlob blob);
+
+extern InspectorPtr NewInspector(IsolatePtr iso_ptr);
+extern void InspectorDelete(InspectorPtr ptr);
+extern void InspectorContextCreated(InspectorPtr ptr,
+                                    ContextPtr ctx_ptr,
+                                    const char* name);
+extern void InspectorContextDestroyed(InspectorPtr ptr, ContextPtr ctx_ptr);
+extern void InspectorRequestInterrupt(InspectorPtr ptr);
+extern void InspectorRunPending(InspectorPtr ptr);
+extern InspectorSessionPtr InspectorConnect(InspectorPtr ptr, int session_ref);
+extern void InspectorSessionDispatchMessage(InspectorSessionPtr ptr,
+                                            const uint16_t* message,
+                                            int length);
+extern void InspectorSessionDelete(InspectorSessionPtr ptr);
+
 extern CPUProfiler* NewCPUProfiler(IsolatePtr iso_ptr);
 extern void CPUProfilerDispose(CPUProfiler* ptr);
 extern void CPUProfilerStartProfiling(CPUProfiler* ptr, const char* title);
+extern int CPUProfilerStartProfilingWithOptions(CPUProfiler* ptr,
+                                                const char* title,
+                                                CPUProfilingOptions options);
 extern CPUProfile* CPUProfilerStopProfiling(CPUProfiler* ptr,
                                             const char* title);
 extern void CPUProfileDelete(CPUProfile* ptr);
 
+extern int HeapProfilerStartSampling(IsolatePtr iso_ptr,
+                                     uint64_t sample_interval,
+                                     int stack_depth,
+                                     int flags);
+extern void HeapProfilerStopSampling(IsolatePtr iso_ptr);
+extern AllocationProfileNode* HeapProfilerGetAllocationProfile(
+    IsolatePtr iso_ptr);
+extern void AllocationProfileNodeDelete(AllocationProfileNode* node);
+extern void HeapProfilerTakeHeapSnapshot(IsolatePtr iso_ptr,
+                                         int writer_ref,
+                                         HeapSnapshotOptions options);
+
 extern ContextPtr NewContext(IsolatePtr iso_ptr,
                              TemplatePtr global_template_ptr,
                              int ref);
+extern ContextPtr NewContextFromSnapshot(IsolatePtr iso_ptr,
+                                         size_t index,
+                                         int ref);
 extern int ContextRetainedValueCount(ContextPtr ctx);
 extern void ContextFree(ContextPtr ptr);
 extern RtnValue RunScript(ContextPtr ctx_ptr,
@@ -194,8 +445,23 @@ extern RtnValue ObjectTemplateNewInstance(TemplatePtr ptr, ContextPtr ctx_ptr);
 extern void ObjectTemplateSetInternalFieldCount(TemplatePtr ptr,
                                                 int field_count);
 extern int ObjectTemplateInternalFieldCount(TemplatePtr ptr);
+extern void ObjectTemplateSetAccessor(TemplatePtr ptr,
+                                      const char* name,
+                                      int callback_ref,
+                                      int has_setter,
+                                      int attributes);
+extern void ObjectTemplateSetNamedPropertyHandler(TemplatePtr ptr,
+                                                  int callback_ref,
+                                                  int callbacks);
+extern void ObjectTemplateSetIndexedPropertyHandler(TemplatePtr ptr,
+                                                    int callback_ref,
+                                                    int callbacks);
 
 extern TemplatePtr NewFunctionTemplate(IsolatePtr iso_ptr, int callback_ref);
+extern TemplatePtr FunctionTemplateInstanceTemplate(TemplatePtr ptr);
+extern TemplatePtr FunctionTemplatePrototypeTemplate(TemplatePtr ptr);
+extern void FunctionTemplateInherit(TemplatePtr ptr, TemplatePtr parent_ptr);
+extern void FunctionTemplateSetClassName(TemplatePtr ptr, const char* name);
 extern RtnValue FunctionTemplateGetFunction(TemplatePtr ptr,
                                             ContextPtr ctx_ptr);
 
@@ -281,6 +547,11 @@ int ValueIsModuleNamespaceObject(ValuePtr ptr);
 
 extern void ObjectSet(ValuePtr ptr, const char* key, ValuePtr val_ptr);
 extern void ObjectSetIdx(ValuePtr ptr, uint32_t idx, ValuePtr val_ptr);
+extern RtnBool ObjectSetAccessor(ValuePtr ptr,
+                                 const char* key,
+                                 int callback_ref,
+                                 int has_setter,
+                                 int attributes);
 extern int ObjectSetInternalField(ValuePtr ptr, int idx, ValuePtr val_ptr);
 extern int ObjectInternalFieldCount(ValuePtr ptr);
 extern RtnValue ObjectGet(ValuePtr ptr, const char* key);